@@ -0,0 +1,30 @@
+// Package apierror writes the error responses of the JSON API, for the
+// handlers and the middlewares in front of them alike.
+package apierror
+
+import (
+	"net/http"
+
+	"github.com/lozhkindm/celeritas"
+)
+
+// Response is the error shape every API response uses. Errors holds the
+// message for each invalid field of a request that failed validation.
+type Response struct {
+	Error   bool              `json:"error"`
+	Message string            `json:"message"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+// Write answers with message and status, logging to app when the response
+// cannot be written.
+func Write(app *celeritas.Celeritas, w http.ResponseWriter, status int, message string) {
+	WriteResponse(app, w, status, Response{Error: true, Message: message})
+}
+
+// WriteResponse answers with res and status.
+func WriteResponse(app *celeritas.Celeritas, w http.ResponseWriter, status int, res Response) {
+	if err := app.WriteJSON(w, status, res); err != nil {
+		app.ErrorLog.Println("error writing json:", err)
+	}
+}
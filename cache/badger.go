@@ -0,0 +1,229 @@
+package cache
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/lozhkindm/celeritas/cache"
+)
+
+// badgerDriver adds the operations Store needs to celeritas's BadgerCache,
+// which stores keys without a prefix. A tagged key is recorded as an empty
+// index key, tagIndex(tag, key), for each tag, and its tags under
+// keyTags(key), all expiring with it.
+type badgerDriver struct {
+	*cache.BadgerCache
+}
+
+func (b *badgerDriver) entryKey(key string) string {
+	return key
+}
+
+func (b *badgerDriver) getRaw(keys []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		for _, key := range keys {
+			item, err := txn.Get([]byte(key))
+			if errors.Is(err, badger.ErrKeyNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if values[key], err = item.ValueCopy(nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return values, err
+}
+
+func (b *badgerDriver) setRaw(values map[string][]byte, ttl time.Duration, tags []string) error {
+	return b.update(func(txn *badger.Txn) error {
+		for key, value := range values {
+			if err := put(txn, key, value, ttl, tags); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *badgerDriver) addRaw(key string, value []byte, ttl time.Duration) (bool, error) {
+	var added bool
+	err := b.update(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(key))
+		if err == nil {
+			added = false
+			return nil
+		}
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+
+		added = true
+		return put(txn, key, value, ttl, nil)
+	})
+	return added, err
+}
+
+func (b *badgerDriver) incrBy(key string, by int64) (int64, error) {
+	var n int64
+	err := b.update(func(txn *badger.Txn) error {
+		n = 0
+		var expiresAt uint64
+		item, err := txn.Get([]byte(key))
+		switch {
+		case err == nil:
+			value, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			var isCounter bool
+			if n, isCounter = parseCounter(value); !isCounter {
+				return ErrNotInteger
+			}
+			expiresAt = item.ExpiresAt()
+		case errors.Is(err, badger.ErrKeyNotFound):
+			if err := untag(txn, key); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+
+		n += by
+		e := badger.NewEntry([]byte(key), []byte(strconv.FormatInt(n, 10)))
+		e.ExpiresAt = expiresAt
+		return txn.SetEntry(e)
+	})
+	return n, err
+}
+
+func (b *badgerDriver) ttl(key string) (time.Duration, error) {
+	var left time.Duration
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+		left = NoExpiry
+		if exp := item.ExpiresAt(); exp > 0 {
+			left = time.Until(time.Unix(int64(exp), 0))
+		}
+		return nil
+	})
+	return left, err
+}
+
+// Forget deletes key and takes it out of its tags.
+func (b *badgerDriver) Forget(key string) error {
+	return b.update(func(txn *badger.Txn) error {
+		if err := untag(txn, key); err != nil {
+			return err
+		}
+		return txn.Delete([]byte(key))
+	})
+}
+
+func (b *badgerDriver) flushTags(tags []string) error {
+	return b.update(func(txn *badger.Txn) error {
+		var keys []string
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		iter := txn.NewIterator(opts)
+		for _, tag := range tags {
+			prefix := tagIndex(tag, "")
+			for iter.Seek(prefix); iter.ValidForPrefix(prefix); iter.Next() {
+				keys = append(keys, string(iter.Item().Key()[len(prefix):]))
+			}
+		}
+		iter.Close()
+
+		for _, key := range keys {
+			if err := untag(txn, key); err != nil {
+				return err
+			}
+			if err := txn.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// put stores value under key, with tags in place of those it had.
+func put(txn *badger.Txn, key string, value []byte, ttl time.Duration, tags []string) error {
+	if err := untag(txn, key); err != nil {
+		return err
+	}
+
+	set := func(key, value []byte) error {
+		e := badger.NewEntry(key, value)
+		if ttl > 0 {
+			e = e.WithTTL(ttl)
+		}
+		return txn.SetEntry(e)
+	}
+	for _, tag := range tags {
+		if err := set(tagIndex(tag, key), nil); err != nil {
+			return err
+		}
+	}
+	if len(tags) > 0 {
+		if err := set(keyTags(key), []byte(strings.Join(tags, "\x00"))); err != nil {
+			return err
+		}
+	}
+	return set([]byte(key), value)
+}
+
+// untag takes key out of the tags it has.
+func untag(txn *badger.Txn, key string) error {
+	item, err := txn.Get(keyTags(key))
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	tags, err := item.ValueCopy(nil)
+	if err != nil {
+		return err
+	}
+	for _, tag := range strings.Split(string(tags), "\x00") {
+		if err := txn.Delete(tagIndex(tag, key)); err != nil {
+			return err
+		}
+	}
+	return txn.Delete(keyTags(key))
+}
+
+// tagIndex returns the index key that records key under tag. The NUL keeps
+// tag "a" from matching the keys of tag "a:b".
+func tagIndex(tag, key string) []byte {
+	return []byte(tagPrefix + tag + "\x00" + key)
+}
+
+// keyTags returns the key that holds the tags of key, separated by NULs.
+func keyTags(key string) []byte {
+	return []byte(keyTagsPrefix + key)
+}
+
+// update runs fn in a read-write transaction, again when it conflicts with
+// a concurrent one.
+func (b *badgerDriver) update(fn func(txn *badger.Txn) error) error {
+	for {
+		err := b.Conn.Update(fn)
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+	}
+}
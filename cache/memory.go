@@ -0,0 +1,350 @@
+// Package cache adds cache drivers to those of celeritas. Memory keeps the
+// cache in the process, for tests and deployments that run a single
+// instance.
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/gob"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lozhkindm/celeritas/cache"
+)
+
+// ErrNotFound is returned by Get for a key that is missing or has expired.
+var ErrNotFound = errors.New("cache: key not found")
+
+// MemoryOptions limit a Memory cache. A zero limit means no limit.
+type MemoryOptions struct {
+	// MaxEntries is the most entries kept; the least recently used are
+	// evicted to make room.
+	MaxEntries int
+	// MaxBytes is the most bytes of encoded values kept, evicting as for
+	// MaxEntries.
+	MaxBytes int
+	// JanitorInterval is how often expired entries are removed in the
+	// background. They are never returned once expired in any case. It
+	// defaults to a minute.
+	JanitorInterval time.Duration
+}
+
+// Memory is a cache.Cache that lives in the process. Values are gob encoded
+// like in the Redis and Badger drivers, so the same types can be stored and
+// a value read back is a copy. It is safe for concurrent use.
+type Memory struct {
+	opts MemoryOptions
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // front is the most recently used
+	size    int
+	tags    map[string]map[string]struct{} // tag to the keys it holds
+
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+type memoryEntry struct {
+	key     string
+	value   []byte
+	expires time.Time // zero for no expiry
+	tags    []string
+}
+
+var _ cache.Cache = (*Memory)(nil)
+
+// NewMemory returns an empty Memory cache and starts its janitor. Call Close
+// to stop it.
+func NewMemory(opts MemoryOptions) *Memory {
+	if opts.JanitorInterval <= 0 {
+		opts.JanitorInterval = time.Minute
+	}
+	m := &Memory{
+		opts:    opts,
+		now:     time.Now,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+		tags:    make(map[string]map[string]struct{}),
+		stop:    make(chan struct{}),
+	}
+	go m.janitor()
+	return m
+}
+
+func (m *Memory) Has(key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.lookup(key)
+	return ok, nil
+}
+
+func (m *Memory) Get(key string) (interface{}, error) {
+	m.mu.Lock()
+	e, ok := m.lookup(key)
+	var value []byte
+	if ok {
+		m.lru.MoveToFront(m.entries[key])
+		value = e.value
+	}
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, ErrNotFound
+	}
+	entry := cache.Entry{}
+	if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&entry); err != nil {
+		return nil, err
+	}
+	return entry[key], nil
+}
+
+// Set stores val under key, for expires[0] seconds when given and positive,
+// or until it is evicted otherwise.
+func (m *Memory) Set(key string, val interface{}, expires ...int) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cache.Entry{key: val}); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var ttl time.Duration
+	if len(expires) > 0 && expires[0] > 0 {
+		ttl = time.Duration(expires[0]) * time.Second
+	}
+	m.put(key, buf.Bytes(), m.expiry(ttl), nil)
+	return nil
+}
+
+func (m *Memory) Forget(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+	return nil
+}
+
+func (m *Memory) Empty() error {
+	return m.EmptyByMatch("")
+}
+
+// EmptyByMatch removes every key that starts with prefix, as the Badger
+// driver does and the Redis driver does for prefixes without glob
+// characters.
+func (m *Memory) EmptyByMatch(prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, el := range m.entries {
+		if strings.HasPrefix(key, prefix) {
+			m.remove(el)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of entries, including expired ones the janitor has
+// not removed yet.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// Close stops the janitor. The cache can still be used afterwards.
+func (m *Memory) Close() error {
+	m.closeOnce.Do(func() { close(m.stop) })
+	return nil
+}
+
+func (m *Memory) entryKey(key string) string {
+	return key
+}
+
+func (m *Memory) getRaw(keys []string) (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := make(map[string][]byte, len(keys))
+	for _, key := range keys {
+		if e, ok := m.lookup(key); ok {
+			m.lru.MoveToFront(m.entries[key])
+			values[key] = e.value
+		}
+	}
+	return values, nil
+}
+
+func (m *Memory) setRaw(values map[string][]byte, ttl time.Duration, tags []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, value := range values {
+		m.put(key, value, m.expiry(ttl), tags)
+	}
+	return nil
+}
+
+func (m *Memory) addRaw(key string, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.lookup(key); ok {
+		return false, nil
+	}
+	m.put(key, value, m.expiry(ttl), nil)
+	return true, nil
+}
+
+func (m *Memory) incrBy(key string, by int64) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var n int64
+	var expires time.Time
+	var tags []string
+	if e, ok := m.lookup(key); ok {
+		var isCounter bool
+		if n, isCounter = parseCounter(e.value); !isCounter {
+			return 0, ErrNotInteger
+		}
+		expires, tags = e.expires, e.tags
+	}
+	n += by
+
+	m.put(key, []byte(strconv.FormatInt(n, 10)), expires, tags)
+	return n, nil
+}
+
+func (m *Memory) ttl(key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.lookup(key)
+	if !ok {
+		return 0, ErrNotFound
+	}
+	if e.expires.IsZero() {
+		return NoExpiry, nil
+	}
+	return e.expires.Sub(m.now()), nil
+}
+
+func (m *Memory) flushTags(tags []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, tag := range tags {
+		for key := range m.tags[tag] {
+			if el, ok := m.entries[key]; ok {
+				m.remove(el)
+			}
+		}
+	}
+	return nil
+}
+
+// put stores value under key as the most recently used entry, tagged with
+// tags, then evicts what no longer fits. The caller holds m.mu.
+func (m *Memory) put(key string, value []byte, expires time.Time, tags []string) {
+	e := &memoryEntry{key: key, value: value, expires: expires, tags: tags}
+
+	if el, ok := m.entries[key]; ok {
+		m.remove(el)
+	}
+	m.entries[key] = m.lru.PushFront(e)
+	m.size += len(e.value)
+	for _, tag := range tags {
+		if m.tags[tag] == nil {
+			m.tags[tag] = make(map[string]struct{})
+		}
+		m.tags[tag][key] = struct{}{}
+	}
+	m.evict()
+}
+
+// expiry returns when an entry stored now for ttl expires, or the zero time
+// for no expiry.
+func (m *Memory) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return m.now().Add(ttl)
+}
+
+// lookup returns the live entry for key, removing it if it has expired.
+func (m *Memory) lookup(key string) (*memoryEntry, bool) {
+	el, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*memoryEntry)
+	if m.expired(e, m.now()) {
+		m.remove(el)
+		return nil, false
+	}
+	return e, true
+}
+
+func (m *Memory) expired(e *memoryEntry, now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// remove drops an entry, and with it the tags that hold no other key.
+func (m *Memory) remove(el *list.Element) {
+	e := m.lru.Remove(el).(*memoryEntry)
+	delete(m.entries, e.key)
+	m.size -= len(e.value)
+	for _, tag := range e.tags {
+		delete(m.tags[tag], e.key)
+		if len(m.tags[tag]) == 0 {
+			delete(m.tags, tag)
+		}
+	}
+}
+
+// evict drops the least recently used entries until the cache is within its
+// limits. An entry larger than MaxBytes on its own is not kept either.
+func (m *Memory) evict() {
+	for m.lru.Len() > 0 &&
+		((m.opts.MaxEntries > 0 && m.lru.Len() > m.opts.MaxEntries) ||
+			(m.opts.MaxBytes > 0 && m.size > m.opts.MaxBytes)) {
+		m.remove(m.lru.Back())
+	}
+}
+
+func (m *Memory) janitor() {
+	ticker := time.NewTicker(m.opts.JanitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.removeExpired()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+func (m *Memory) removeExpired() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	for _, el := range m.entries {
+		if m.expired(el.Value.(*memoryEntry), now) {
+			m.remove(el)
+		}
+	}
+}
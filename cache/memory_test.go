@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testMemory returns a Memory whose clock is *now.
+func testMemory(t *testing.T, opts MemoryOptions, now *time.Time) *Memory {
+	t.Helper()
+	m := NewMemory(opts)
+	m.now = func() time.Time { return *now }
+	t.Cleanup(func() { _ = m.Close() })
+	return m
+}
+
+// has reports which of keys m holds.
+func has(t *testing.T, m *Memory, keys ...string) map[string]bool {
+	t.Helper()
+	found := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		ok, err := m.Has(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		found[key] = ok
+	}
+	return found
+}
+
+func TestMemoryEvictsLeastRecentlyUsed(t *testing.T) {
+	now := time.Now()
+	m := testMemory(t, MemoryOptions{MaxEntries: 3}, &now)
+
+	for _, key := range []string{"a", "b", "c"} {
+		if err := m.Set(key, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// reading a makes b the least recently used
+	if _, err := m.Get("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("d", "d"); err != nil {
+		t.Fatal(err)
+	}
+
+	found := has(t, m, "a", "b", "c", "d")
+	if !found["a"] || found["b"] || !found["c"] || !found["d"] {
+		t.Errorf("expected b to be evicted, got %v", found)
+	}
+	if m.Len() != 3 {
+		t.Errorf("expected 3 entries, got %d", m.Len())
+	}
+
+	// overwriting a key does not take another entry
+	if err := m.Set("c", "again"); err != nil {
+		t.Fatal(err)
+	}
+	if found := has(t, m, "a", "c", "d"); !found["a"] || !found["c"] || !found["d"] {
+		t.Errorf("expected overwriting c to keep every entry, got %v", found)
+	}
+}
+
+func TestMemoryEvictsByBytes(t *testing.T) {
+	now := time.Now()
+	value := strings.Repeat("x", 100)
+
+	// the encoded size of one entry with a one letter key
+	probe := testMemory(t, MemoryOptions{}, &now)
+	if err := probe.Set("a", value); err != nil {
+		t.Fatal(err)
+	}
+	size := probe.size
+
+	m := testMemory(t, MemoryOptions{MaxBytes: 2*size + size/2}, &now)
+	for _, key := range []string{"a", "b", "c"} {
+		if err := m.Set(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if found := has(t, m, "a", "b", "c"); found["a"] || !found["b"] || !found["c"] {
+		t.Errorf("expected a to be evicted to make room, got %v", found)
+	}
+	if m.size > m.opts.MaxBytes {
+		t.Errorf("expected at most %d bytes, got %d", m.opts.MaxBytes, m.size)
+	}
+
+	// an entry larger than MaxBytes is not kept, and takes the others along
+	if err := m.Set("big", strings.Repeat("x", 3*size)); err != nil {
+		t.Fatal(err)
+	}
+	if found := has(t, m, "b", "c", "big"); found["b"] || found["c"] || found["big"] {
+		t.Errorf("expected nothing to be kept, got %v", found)
+	}
+	if m.Len() != 0 || m.size != 0 {
+		t.Errorf("expected an empty cache, got %d entries of %d bytes", m.Len(), m.size)
+	}
+}
+
+func TestMemoryExpiresOnRead(t *testing.T) {
+	now := time.Now()
+	m := testMemory(t, MemoryOptions{}, &now)
+
+	if err := m.Set("short", "value", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("forever", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("zero", "value", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(9 * time.Second)
+	if got, err := m.Get("short"); err != nil || got != "value" {
+		t.Fatalf("expected the value before it expires, got %v, %v", got, err)
+	}
+
+	now = now.Add(time.Second)
+	if _, err := m.Get("short"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound once expired, got %v", err)
+	}
+	if m.Len() != 2 {
+		t.Errorf("expected the expired entry to be removed when read, got %d entries", m.Len())
+	}
+
+	now = now.Add(24 * time.Hour)
+	if found := has(t, m, "forever", "zero"); !found["forever"] || !found["zero"] {
+		t.Errorf("expected entries without expiry to stay, got %v", found)
+	}
+}
+
+func TestMemoryRemoveExpired(t *testing.T) {
+	now := time.Now()
+	m := testMemory(t, MemoryOptions{}, &now)
+
+	if err := m.Set("soon", "value", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("later", "value", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("forever", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(30 * time.Second)
+	m.removeExpired()
+	if m.Len() != 2 {
+		t.Errorf("expected the janitor to remove one entry, got %d left", m.Len())
+	}
+
+	now = now.Add(time.Hour)
+	m.removeExpired()
+	if found := has(t, m, "forever"); m.Len() != 1 || !found["forever"] {
+		t.Errorf("expected only the entry without expiry to be left, got %d entries", m.Len())
+	}
+}
+
+func TestMemoryJanitorRuns(t *testing.T) {
+	now := time.Now()
+	m := NewMemory(MemoryOptions{JanitorInterval: 10 * time.Millisecond})
+	t.Cleanup(func() { _ = m.Close() })
+
+	m.mu.Lock()
+	m.put("stale", []byte("value"), now.Add(-time.Second), nil)
+	m.mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Len() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the janitor to remove the expired entry")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMemoryEmptyByMatch(t *testing.T) {
+	now := time.Now()
+	m := testMemory(t, MemoryOptions{}, &now)
+
+	for _, key := range []string{"user:1", "user:10", "user:1:posts", "users", "post:user:1"} {
+		if err := m.Set(key, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := m.EmptyByMatch("user:1"); err != nil {
+		t.Fatal(err)
+	}
+	found := has(t, m, "user:1", "user:10", "user:1:posts", "users", "post:user:1")
+	if found["user:1"] || found["user:10"] || found["user:1:posts"] {
+		t.Errorf("expected every key starting with user:1 to be removed, got %v", found)
+	}
+	if !found["users"] || !found["post:user:1"] {
+		t.Errorf("expected keys only containing user:1 to stay, got %v", found)
+	}
+
+	// a glob is matched literally
+	if err := m.EmptyByMatch("user*"); err != nil {
+		t.Fatal(err)
+	}
+	if !has(t, m, "users")["users"] {
+		t.Error("expected * not to be a wildcard")
+	}
+
+	if err := m.Empty(); err != nil {
+		t.Fatal(err)
+	}
+	if m.Len() != 0 || m.size != 0 {
+		t.Errorf("expected Empty to remove everything, got %d entries of %d bytes", m.Len(), m.size)
+	}
+}
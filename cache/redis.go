@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/lozhkindm/celeritas/cache"
+)
+
+// untagLua removes a key from the tag sets its own set of tags names, and
+// deletes that set. The scripts below start with it.
+const untagLua = `
+local function untag(tagsOf, tagPrefix, key)
+	for _, tag in ipairs(redis.call('SMEMBERS', tagsOf)) do
+		redis.call('SREM', tagPrefix .. tag, key)
+	end
+	redis.call('DEL', tagsOf)
+end
+`
+
+// setScript stores ARGV[1] at KEYS[1] for ARGV[2] milliseconds, or with no
+// expiry when it is 0, and only if the key is missing when ARGV[3] is "1".
+// It then replaces the tags of key ARGV[5], kept in the set KEYS[2], with
+// ARGV[6:], adding the key to the tag sets named by prefix ARGV[4]. A tag
+// set lives as long as its longest-lived key. It returns 1 when it stored
+// the value.
+var setScript = redis.NewScript(2, untagLua+`
+local ms = tonumber(ARGV[2])
+local args = {KEYS[1], ARGV[1]}
+if ms > 0 then
+	args[#args + 1] = 'PX'
+	args[#args + 1] = ms
+end
+if ARGV[3] == '1' then
+	args[#args + 1] = 'NX'
+end
+if not redis.call('SET', unpack(args)) then
+	return 0
+end
+
+untag(KEYS[2], ARGV[4], ARGV[5])
+for i = 6, #ARGV do
+	local tagSet = ARGV[4] .. ARGV[i]
+	local left = redis.call('PTTL', tagSet)
+	redis.call('SADD', tagSet, ARGV[5])
+	redis.call('SADD', KEYS[2], ARGV[i])
+	if ms == 0 then
+		redis.call('PERSIST', tagSet)
+	elseif left == -2 or (left >= 0 and left < ms) then
+		redis.call('PEXPIRE', tagSet, ms)
+	end
+end
+if ms > 0 and #ARGV >= 6 then
+	redis.call('PEXPIRE', KEYS[2], ms)
+end
+return 1
+`)
+
+// incrScript adds ARGV[1] to the counter at KEYS[1], first dropping the
+// tags a missing key ARGV[3] may have been left with, as setScript does.
+var incrScript = redis.NewScript(2, untagLua+`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	untag(KEYS[2], ARGV[2], ARGV[3])
+end
+return redis.call('INCRBY', KEYS[1], ARGV[1])
+`)
+
+// forgetScript deletes KEYS[1] and the tags of key ARGV[2], as setScript
+// does.
+var forgetScript = redis.NewScript(2, untagLua+`
+untag(KEYS[2], ARGV[1], ARGV[2])
+return redis.call('DEL', KEYS[1])
+`)
+
+// flushScript deletes the tag sets in KEYS and every key they hold that is
+// still tagged with them, with the key prefix ARGV[1]. ARGV[2] and ARGV[3]
+// prefix the sets of a key's tags and the tag sets.
+var flushScript = redis.NewScript(-1, untagLua+`
+for _, tagSet in ipairs(KEYS) do
+	local tag = string.sub(tagSet, #ARGV[3] + 1)
+	for _, key in ipairs(redis.call('SMEMBERS', tagSet)) do
+		local tagsOf = ARGV[2] .. key
+		if redis.call('SISMEMBER', tagsOf, tag) == 1 then
+			untag(tagsOf, ARGV[3], key)
+			redis.call('DEL', ARGV[1] .. key)
+		end
+	end
+	redis.call('DEL', tagSet)
+end
+return 0
+`)
+
+// redisDriver adds the operations Store needs to celeritas's RedisCache,
+// using its key prefix. A tag is a set of the keys it holds, and each tagged
+// key has a set of its tags, so that writing or forgetting the key takes it
+// out of them.
+type redisDriver struct {
+	*cache.RedisCache
+}
+
+func (r *redisDriver) key(key string) string {
+	return fmt.Sprintf("%s:%s", r.Prefix, key)
+}
+
+// entryKey is the prefixed key, which RedisCache.Get decodes by.
+func (r *redisDriver) entryKey(key string) string {
+	return r.key(key)
+}
+
+func (r *redisDriver) do(fn func(conn redis.Conn) error) error {
+	conn := r.Conn.Get()
+	defer func() {
+		_ = conn.Close()
+	}()
+	return fn(conn)
+}
+
+func (r *redisDriver) getRaw(keys []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+
+	args := make([]interface{}, len(keys))
+	for i, key := range keys {
+		args[i] = r.key(key)
+	}
+	err := r.do(func(conn redis.Conn) error {
+		replies, err := redis.ByteSlices(conn.Do("MGET", args...))
+		if err != nil {
+			return err
+		}
+		for i, b := range replies {
+			if b != nil {
+				values[keys[i]] = b
+			}
+		}
+		return nil
+	})
+	return values, err
+}
+
+func (r *redisDriver) setRaw(values map[string][]byte, ttl time.Duration, tags []string) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return r.do(func(conn redis.Conn) error {
+		if err := conn.Send("MULTI"); err != nil {
+			return err
+		}
+		for key, value := range values {
+			if err := setScript.Send(conn, r.setArgs(key, value, ttl, false, tags)...); err != nil {
+				return err
+			}
+		}
+		replies, err := redis.Values(conn.Do("EXEC"))
+		if err != nil {
+			return err
+		}
+		for _, reply := range replies {
+			if err, ok := reply.(redis.Error); ok {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *redisDriver) addRaw(key string, value []byte, ttl time.Duration) (bool, error) {
+	var added bool
+	err := r.do(func(conn redis.Conn) error {
+		n, err := redis.Int(setScript.Do(conn, r.setArgs(key, value, ttl, true, nil)...))
+		added = n == 1
+		return err
+	})
+	return added, err
+}
+
+func (r *redisDriver) incrBy(key string, by int64) (int64, error) {
+	var n int64
+	err := r.do(func(conn redis.Conn) error {
+		var err error
+		n, err = redis.Int64(incrScript.Do(conn, r.key(key), r.key(keyTagsPrefix+key), by, r.key(tagPrefix), key))
+		var redisErr redis.Error
+		if errors.As(err, &redisErr) && strings.Contains(redisErr.Error(), "not an integer") {
+			return ErrNotInteger
+		}
+		return err
+	})
+	return n, err
+}
+
+// Forget deletes key and takes it out of its tags.
+func (r *redisDriver) Forget(key string) error {
+	return r.do(func(conn redis.Conn) error {
+		_, err := forgetScript.Do(conn, r.key(key), r.key(keyTagsPrefix+key), r.key(tagPrefix), key)
+		return err
+	})
+}
+
+func (r *redisDriver) ttl(key string) (time.Duration, error) {
+	var ms int64
+	err := r.do(func(conn redis.Conn) error {
+		var err error
+		ms, err = redis.Int64(conn.Do("PTTL", r.key(key)))
+		return err
+	})
+	switch {
+	case err != nil:
+		return 0, err
+	case ms == -2:
+		return 0, ErrNotFound
+	case ms == -1:
+		return NoExpiry, nil
+	default:
+		return time.Duration(ms) * time.Millisecond, nil
+	}
+}
+
+func (r *redisDriver) flushTags(tags []string) error {
+	args := []interface{}{len(tags)}
+	for _, tag := range tags {
+		args = append(args, r.key(tagPrefix+tag))
+	}
+	args = append(args, r.key(""), r.key(keyTagsPrefix), r.key(tagPrefix))
+	return r.do(func(conn redis.Conn) error {
+		_, err := flushScript.Do(conn, args...)
+		return err
+	})
+}
+
+// setArgs returns the keys and arguments of setScript.
+func (r *redisDriver) setArgs(key string, value []byte, ttl time.Duration, nx bool, tags []string) []interface{} {
+	onlyNew := "0"
+	if nx {
+		onlyNew = "1"
+	}
+	args := []interface{}{r.key(key), r.key(keyTagsPrefix + key), value, milliseconds(ttl), onlyNew, r.key(tagPrefix), key}
+	for _, tag := range tags {
+		args = append(args, tag)
+	}
+	return args
+}
+
+// milliseconds returns ttl in whole milliseconds, at least 1 for a positive
+// ttl, and 0 for no expiry.
+func milliseconds(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	if ms := ttl.Milliseconds(); ms > 0 {
+		return ms
+	}
+	return 1
+}
@@ -0,0 +1,357 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lozhkindm/celeritas/cache"
+)
+
+// NoExpiry is what TTL returns for a key that never expires.
+const NoExpiry time.Duration = -1
+
+var (
+	// ErrUnsupported is returned by NewStore for a cache.Cache it has no
+	// driver for.
+	ErrUnsupported = errors.New("cache: unsupported cache driver")
+	// ErrNotInteger is returned by Increment and Decrement when the key
+	// holds something other than a counter.
+	ErrNotInteger = errors.New("cache: value is not an integer")
+	// ErrNegativeTTL is returned by the methods taking a ttl when it is
+	// below 0. A ttl of 0 means no expiry.
+	ErrNegativeTTL = errors.New("cache: ttl must not be negative")
+)
+
+// driver is what each cache backend does natively. Store builds the rest of
+// its API on top, so every backend behaves the same.
+type driver interface {
+	cache.Cache
+	// getRaw returns the stored bytes of the keys that exist.
+	getRaw(keys []string) (map[string][]byte, error)
+	// setRaw stores every value, expiring after ttl unless ttl is 0, and
+	// records the keys under each of tags for at least as long.
+	setRaw(values map[string][]byte, ttl time.Duration, tags []string) error
+	// addRaw stores value unless key exists, and reports whether it did.
+	addRaw(key string, value []byte, ttl time.Duration) (bool, error)
+	// incrBy adds by to the counter at key, starting from 0, and keeps its
+	// expiry.
+	incrBy(key string, by int64) (int64, error)
+	// ttl returns the time key has left, NoExpiry, or ErrNotFound.
+	ttl(key string) (time.Duration, error)
+	// flushTags removes every key recorded under tags, and the tags.
+	flushTags(tags []string) error
+	// entryKey returns the name Get looks key up by in the cache.Entry it
+	// decodes.
+	entryKey(key string) string
+}
+
+// Store is a cache.Cache with a richer API: typed values, Remember, counters
+// and bulk operations. Values written by Put, Add, SetMany and Remember are
+// gob encoded as their own type, so they are read back with Load or GetMany
+// into a variable of that type, and custom types need no gob.Register. Get
+// only reads values written by Set.
+// A ttl of 0 means no expiry, and a negative one is refused with
+// ErrNegativeTTL. Durations are rounded down to whole seconds by the Badger
+// driver and to milliseconds by the others.
+type Store struct {
+	cache.Cache
+
+	// OnError, when set, is told about cache failures that Remember works
+	// around.
+	OnError func(error)
+
+	d      driver
+	flight flight
+}
+
+// NewStore wraps one of the caches celeritas creates, or a Memory cache.
+func NewStore(c cache.Cache) (*Store, error) {
+	var d driver
+	switch c := c.(type) {
+	case *Memory:
+		d = c
+	case *cache.RedisCache:
+		d = &redisDriver{c}
+	case *cache.BadgerCache:
+		d = &badgerDriver{c}
+	default:
+		return nil, fmt.Errorf("%w %T", ErrUnsupported, c)
+	}
+	return &Store{Cache: c, d: d}, nil
+}
+
+// Set stores val under key like cache.Cache's Set, so Get reads it back:
+// for expires[0] seconds when given and positive, or with no expiry. It
+// takes key out of any tags it had.
+func (s *Store) Set(key string, val interface{}, expires ...int) error {
+	return s.set(key, val, expires, nil)
+}
+
+func (s *Store) set(key string, val interface{}, expires []int, tags []string) error {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cache.Entry{s.d.entryKey(key): val}); err != nil {
+		return err
+	}
+	var ttl time.Duration
+	if len(expires) > 0 && expires[0] > 0 {
+		ttl = time.Duration(expires[0]) * time.Second
+	}
+	return s.d.setRaw(map[string][]byte{key: buf.Bytes()}, ttl, tags)
+}
+
+// Forget deletes key and takes it out of any tags it had.
+func (s *Store) Forget(key string) error {
+	return s.d.Forget(key)
+}
+
+// Has reports whether key exists, whichever method stored it.
+func (s *Store) Has(key string) (bool, error) {
+	values, err := s.d.getRaw([]string{key})
+	return len(values) > 0, err
+}
+
+// Load reads key into dst, a pointer to a variable of the type the value was
+// stored as. It reports false when the key is missing.
+func (s *Store) Load(key string, dst interface{}) (bool, error) {
+	values, err := s.d.getRaw([]string{key})
+	if err != nil {
+		return false, err
+	}
+	b, ok := values[key]
+	if !ok {
+		return false, nil
+	}
+	return true, decodeValue(b, dst)
+}
+
+// Put stores val under key, expiring after ttl unless ttl is 0.
+func (s *Store) Put(key string, val interface{}, ttl time.Duration) error {
+	return s.SetMany(map[string]interface{}{key: val}, ttl)
+}
+
+// Add stores val under key unless the key exists, and reports whether it
+// did. It is atomic, so only one of several callers adding the same key
+// succeeds.
+func (s *Store) Add(key string, val interface{}, ttl time.Duration) (bool, error) {
+	if ttl < 0 {
+		return false, ErrNegativeTTL
+	}
+	b, err := encodeValue(val)
+	if err != nil {
+		return false, err
+	}
+	return s.d.addRaw(key, b, ttl)
+}
+
+// GetMany reads the keys that exist into dst, a pointer to a map from string
+// to the type the values were stored as. Missing keys are left out.
+func (s *Store) GetMany(dst interface{}, keys ...string) error {
+	m := reflect.ValueOf(dst)
+	if m.Kind() != reflect.Ptr || m.Elem().Kind() != reflect.Map || m.Elem().Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("cache: GetMany needs a pointer to a map with string keys, got %T", dst)
+	}
+	m = m.Elem()
+	if m.IsNil() {
+		m.Set(reflect.MakeMap(m.Type()))
+	}
+
+	values, err := s.d.getRaw(keys)
+	if err != nil {
+		return err
+	}
+	for key, b := range values {
+		v := reflect.New(m.Type().Elem())
+		if err := decodeValue(b, v.Interface()); err != nil {
+			return fmt.Errorf("cache: %s: %w", key, err)
+		}
+		m.SetMapIndex(reflect.ValueOf(key).Convert(m.Type().Key()), v.Elem())
+	}
+	return nil
+}
+
+// SetMany stores every value, expiring after ttl unless ttl is 0.
+func (s *Store) SetMany(values map[string]interface{}, ttl time.Duration) error {
+	return s.setMany(values, ttl, nil)
+}
+
+func (s *Store) setMany(values map[string]interface{}, ttl time.Duration, tags []string) error {
+	if ttl < 0 {
+		return ErrNegativeTTL
+	}
+	encoded := make(map[string][]byte, len(values))
+	for key, val := range values {
+		b, err := encodeValue(val)
+		if err != nil {
+			return fmt.Errorf("cache: %s: %w", key, err)
+		}
+		encoded[key] = b
+	}
+	return s.d.setRaw(encoded, ttl, tags)
+}
+
+// Remember reads key into dst or, when it is missing, calls fn, stores its
+// result for ttl and reads that into dst. Concurrent misses on the same key
+// in this process call fn once and share its result. fn's errors are
+// returned and nothing is stored; a cache that cannot be read or written is
+// reported to OnError and fn's result is used all the same.
+func (s *Store) Remember(key string, ttl time.Duration, dst interface{}, fn func() (interface{}, error)) error {
+	return s.remember(key, ttl, dst, fn, nil)
+}
+
+func (s *Store) remember(key string, ttl time.Duration, dst interface{}, fn func() (interface{}, error), tags []string) error {
+	if ttl < 0 {
+		return ErrNegativeTTL
+	}
+	found, err := s.Load(key, dst)
+	if err != nil {
+		s.report(err)
+	}
+	if found && err == nil {
+		return nil
+	}
+
+	b, err := s.flight.do(key, func() ([]byte, error) {
+		val, err := fn()
+		if err != nil {
+			return nil, err
+		}
+		b, err := encodeValue(val)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.d.setRaw(map[string][]byte{key: b}, ttl, tags); err != nil {
+			s.report(err)
+		}
+		return b, nil
+	})
+	if err != nil {
+		return err
+	}
+	return decodeValue(b, dst)
+}
+
+// Increment adds by to the counter at key and returns its new value. A
+// missing counter starts from 0 and never expires; an existing one keeps its
+// expiry.
+func (s *Store) Increment(key string, by int64) (int64, error) {
+	return s.d.incrBy(key, by)
+}
+
+// Decrement subtracts by from the counter at key, as Increment adds.
+func (s *Store) Decrement(key string, by int64) (int64, error) {
+	return s.d.incrBy(key, -by)
+}
+
+// TTL returns the time left before key expires, NoExpiry for a key that
+// never does, or ErrNotFound.
+func (s *Store) TTL(key string) (time.Duration, error) {
+	return s.d.ttl(key)
+}
+
+func (s *Store) report(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+func encodeValue(val interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(val); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeValue reads b into dst. Besides values from encodeValue it reads
+// counters into integers, and values written by Set into a variable of their
+// type.
+func decodeValue(b []byte, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("cache: cannot decode into %T", dst)
+	}
+	v = v.Elem()
+
+	if n, ok := parseCounter(b); ok {
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.SetInt(n)
+			return nil
+		}
+	}
+
+	// gob would merge a map into the one already there
+	v.Set(reflect.Zero(v.Type()))
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(dst)
+	if err == nil {
+		return nil
+	}
+
+	entry := cache.Entry{}
+	if gob.NewDecoder(bytes.NewReader(b)).Decode(&entry) == nil && len(entry) == 1 {
+		for _, val := range entry {
+			if rv := reflect.ValueOf(val); rv.IsValid() && rv.Type().AssignableTo(v.Type()) {
+				v.Set(rv)
+				return nil
+			}
+		}
+	}
+	return err
+}
+
+// parseCounter reads a counter, which is stored as decimal text so Redis can
+// increment it.
+func parseCounter(b []byte) (int64, bool) {
+	if len(b) == 0 || len(b) > 20 {
+		return 0, false
+	}
+	for i, c := range b {
+		if (c < '0' || c > '9') && !(i == 0 && c == '-' && len(b) > 1) {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseInt(string(b), 10, 64)
+	return n, err == nil
+}
+
+// flight makes concurrent calls for the same key share one call of fn.
+type flight struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	done chan struct{}
+	val  []byte
+	err  error
+}
+
+func (f *flight) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	f.mu.Lock()
+	if c, ok := f.calls[key]; ok {
+		f.mu.Unlock()
+		<-c.done
+		return c.val, c.err
+	}
+	if f.calls == nil {
+		f.calls = make(map[string]*flightCall)
+	}
+	c := &flightCall{done: make(chan struct{})}
+	f.calls[key] = c
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		delete(f.calls, key)
+		f.mu.Unlock()
+		close(c.done)
+	}()
+	c.val, c.err = fn()
+	return c.val, c.err
+}
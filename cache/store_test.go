@@ -0,0 +1,369 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/gomodule/redigo/redis"
+	"github.com/lozhkindm/celeritas/cache"
+)
+
+// testStores returns a Store over each driver: Memory, Badger in a
+// temporary directory, and Redis against an in-process server.
+func testStores(t *testing.T) map[string]*Store {
+	t.Helper()
+
+	memory := NewMemory(MemoryOptions{})
+	t.Cleanup(func() { _ = memory.Close() })
+
+	db, err := badger.Open(badger.DefaultOptions(t.TempDir()).WithLogger(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	srv := miniredis.RunT(t)
+	pool := &redis.Pool{Dial: func() (redis.Conn, error) {
+		return redis.Dial("tcp", srv.Addr())
+	}}
+	t.Cleanup(func() { _ = pool.Close() })
+
+	stores := make(map[string]*Store)
+	for name, c := range map[string]cache.Cache{
+		"memory": memory,
+		"badger": &cache.BadgerCache{Conn: db},
+		"redis":  &cache.RedisCache{Conn: pool, Prefix: "test"},
+	} {
+		s, err := NewStore(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stores[name] = s
+	}
+	return stores
+}
+
+func TestTaggedSetRoundTrip(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Tags("posts").Set("greeting", "hello", 60); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := s.Get("greeting")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != "hello" {
+				t.Errorf("expected Get to return %q, got %v", "hello", got)
+			}
+
+			var loaded string
+			if found, err := s.Load("greeting", &loaded); err != nil || !found {
+				t.Fatalf("expected Load to find the value, got %v, %v", found, err)
+			}
+			if loaded != "hello" {
+				t.Errorf("expected Load to return %q, got %q", "hello", loaded)
+			}
+		})
+	}
+}
+
+func TestTagsFlush(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			posts := s.Tags("user:42", "posts")
+			for _, key := range []string{"tagged", "overwritten", "forgotten", "added"} {
+				if err := posts.Put(key, 1, time.Hour); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := s.Tags("user:420").Put("other-tag", 1, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := s.Put("overwritten", 2, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Forget("forgotten"); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Set("forgotten", 2); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Forget("added"); err != nil {
+				t.Fatal(err)
+			}
+			if added, err := s.Add("added", 2, time.Hour); err != nil || !added {
+				t.Fatalf("expected Add to store the value, got %v, %v", added, err)
+			}
+
+			if err := s.Tags("user:42").Flush(); err != nil {
+				t.Fatal(err)
+			}
+			for key, want := range map[string]bool{
+				"tagged":      false,
+				"overwritten": true,
+				"forgotten":   true,
+				"added":       true,
+				"other-tag":   true,
+			} {
+				if found, err := s.Has(key); err != nil || found != want {
+					t.Errorf("expected %s to exist: %v, got %v, %v", key, want, found, err)
+				}
+			}
+
+			if err := s.Tags("user:420").Flush(); err != nil {
+				t.Fatal(err)
+			}
+			if found, _ := s.Has("other-tag"); found {
+				t.Error("expected other-tag to be flushed")
+			}
+		})
+	}
+}
+
+func TestRememberCallsFnOnce(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			var calls int32
+			release := make(chan struct{})
+			fn := func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return "computed", nil
+			}
+
+			const callers = 10
+			var started, wg sync.WaitGroup
+			started.Add(callers)
+			wg.Add(callers)
+			results := make([]string, callers)
+			errs := make([]error, callers)
+			for i := 0; i < callers; i++ {
+				go func(i int) {
+					defer wg.Done()
+					started.Done()
+					errs[i] = s.Remember("shared", time.Hour, &results[i], fn)
+				}(i)
+			}
+			started.Wait()
+			// let the callers reach fn before it returns
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+			wg.Wait()
+
+			if n := atomic.LoadInt32(&calls); n != 1 {
+				t.Errorf("expected fn to be called once, got %d", n)
+			}
+			for i := range results {
+				if errs[i] != nil || results[i] != "computed" {
+					t.Errorf("caller %d: expected %q, got %q, %v", i, "computed", results[i], errs[i])
+				}
+			}
+
+			var got string
+			err := s.Remember("shared", time.Hour, &got, func() (interface{}, error) {
+				t.Error("expected a stored value not to call fn")
+				return nil, nil
+			})
+			if err != nil || got != "computed" {
+				t.Errorf("expected the stored value, got %q, %v", got, err)
+			}
+		})
+	}
+}
+
+func TestRememberStoresNothingOnError(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			failed := errors.New("failed")
+			var got string
+			err := s.Remember("failing", time.Hour, &got, func() (interface{}, error) {
+				return nil, failed
+			})
+			if !errors.Is(err, failed) {
+				t.Errorf("expected fn's error, got %v", err)
+			}
+			if found, _ := s.Has("failing"); found {
+				t.Error("expected nothing to be stored")
+			}
+		})
+	}
+}
+
+func TestIncrementAndDecrement(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if n, err := s.Increment("hits", 1); err != nil || n != 1 {
+				t.Fatalf("expected a missing counter to start from 0, got %d, %v", n, err)
+			}
+			if n, err := s.Increment("hits", 5); err != nil || n != 6 {
+				t.Errorf("expected 6, got %d, %v", n, err)
+			}
+			if n, err := s.Decrement("hits", 10); err != nil || n != -4 {
+				t.Errorf("expected -4, got %d, %v", n, err)
+			}
+			if n, err := s.Decrement("misses", 2); err != nil || n != -2 {
+				t.Errorf("expected a missing counter to go below 0, got %d, %v", n, err)
+			}
+
+			var hits int64
+			if found, err := s.Load("hits", &hits); err != nil || !found || hits != -4 {
+				t.Errorf("expected Load to read the counter, got %d, %v, %v", hits, found, err)
+			}
+			if ttl, err := s.TTL("hits"); err != nil || ttl != NoExpiry {
+				t.Errorf("expected a new counter never to expire, got %v, %v", ttl, err)
+			}
+
+			if err := s.Put("name", "ada", 0); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Increment("name", 1); !errors.Is(err, ErrNotInteger) {
+				t.Errorf("expected ErrNotInteger for a string, got %v", err)
+			}
+			if _, err := s.Decrement("name", 1); !errors.Is(err, ErrNotInteger) {
+				t.Errorf("expected ErrNotInteger for a string, got %v", err)
+			}
+			var name string
+			if _, err := s.Load("name", &name); err != nil || name != "ada" {
+				t.Errorf("expected the string to be left alone, got %q, %v", name, err)
+			}
+		})
+	}
+}
+
+func TestGetManyAndSetMany(t *testing.T) {
+	type point struct{ X, Y int }
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.SetMany(map[string]interface{}{
+				"a": point{1, 2},
+				"b": point{3, 4},
+			}, time.Hour); err != nil {
+				t.Fatal(err)
+			}
+
+			got := map[string]point{"stale": {9, 9}}
+			if err := s.GetMany(&got, "a", "b", "missing"); err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 3 || got["a"] != (point{1, 2}) || got["b"] != (point{3, 4}) {
+				t.Errorf("expected a and b added to the map, got %v", got)
+			}
+			if _, ok := got["missing"]; ok {
+				t.Error("expected a missing key to be left out")
+			}
+
+			var fresh map[string]point
+			if err := s.GetMany(&fresh, "a"); err != nil || fresh["a"] != (point{1, 2}) {
+				t.Errorf("expected a nil map to be made, got %v, %v", fresh, err)
+			}
+			if err := s.GetMany(got, "a"); err == nil {
+				t.Error("expected an error for a map that is not a pointer")
+			}
+			var wrong map[string]string
+			if err := s.GetMany(&wrong, "a"); err == nil {
+				t.Error("expected an error decoding into the wrong type")
+			}
+		})
+	}
+}
+
+func TestTTL(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Put("hour", "v", time.Hour); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Put("forever", "v", 0); err != nil {
+				t.Fatal(err)
+			}
+			if err := s.Set("set", "v", 60); err != nil {
+				t.Fatal(err)
+			}
+
+			if ttl, err := s.TTL("hour"); err != nil || ttl <= 59*time.Minute || ttl > time.Hour {
+				t.Errorf("expected about an hour, got %v, %v", ttl, err)
+			}
+			if ttl, err := s.TTL("set"); err != nil || ttl <= 59*time.Second || ttl > time.Minute {
+				t.Errorf("expected about a minute for Set, got %v, %v", ttl, err)
+			}
+			if ttl, err := s.TTL("forever"); err != nil || ttl != NoExpiry {
+				t.Errorf("expected NoExpiry, got %v, %v", ttl, err)
+			}
+			if _, err := s.TTL("missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestAdd(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if added, err := s.Add("lock", "first", time.Hour); err != nil || !added {
+				t.Fatalf("expected the first Add to store the value, got %v, %v", added, err)
+			}
+			if added, err := s.Add("lock", "second", time.Hour); err != nil || added {
+				t.Errorf("expected a second Add to be refused, got %v, %v", added, err)
+			}
+			var got string
+			if _, err := s.Load("lock", &got); err != nil || got != "first" {
+				t.Errorf("expected the first value to be kept, got %q, %v", got, err)
+			}
+			if ttl, err := s.TTL("lock"); err != nil || ttl <= 59*time.Minute {
+				t.Errorf("expected Add to set the expiry, got %v, %v", ttl, err)
+			}
+
+			const callers = 10
+			var wg sync.WaitGroup
+			var wins int32
+			wg.Add(callers)
+			for i := 0; i < callers; i++ {
+				go func(i int) {
+					defer wg.Done()
+					if added, err := s.Add("race", i, 0); err == nil && added {
+						atomic.AddInt32(&wins, 1)
+					}
+				}(i)
+			}
+			wg.Wait()
+			if wins != 1 {
+				t.Errorf("expected one of %d concurrent callers to add the key, got %d", callers, wins)
+			}
+		})
+	}
+}
+
+func TestNegativeTTL(t *testing.T) {
+	for name, s := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			if err := s.Put("k", "v", -time.Second); !errors.Is(err, ErrNegativeTTL) {
+				t.Errorf("expected Put to refuse a negative ttl, got %v", err)
+			}
+			if err := s.SetMany(map[string]interface{}{"k": "v"}, NoExpiry); !errors.Is(err, ErrNegativeTTL) {
+				t.Errorf("expected SetMany to refuse a negative ttl, got %v", err)
+			}
+			if _, err := s.Add("k", "v", -time.Second); !errors.Is(err, ErrNegativeTTL) {
+				t.Errorf("expected Add to refuse a negative ttl, got %v", err)
+			}
+			var got string
+			err := s.Tags("t").Remember("k", -time.Second, &got, func() (interface{}, error) {
+				t.Error("expected fn not to be called")
+				return "v", nil
+			})
+			if !errors.Is(err, ErrNegativeTTL) {
+				t.Errorf("expected Remember to refuse a negative ttl, got %v", err)
+			}
+			if found, _ := s.Has("k"); found {
+				t.Error("expected nothing to be stored")
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package cache
+
+import "time"
+
+// tagPrefix and keyTagsPrefix start the keys that hold tag metadata, so
+// they do not collide with the application's: the keys a tag holds, and the
+// tags a key has.
+const (
+	tagPrefix     = "_tags:"
+	keyTagsPrefix = "_tagged:"
+)
+
+// TaggedStore writes to a Store and records every key it writes under its
+// tags, so that Flush can remove them all whatever they are called. Writing
+// a key again through the Store without tags, or forgetting it, takes it out
+// of them. Tag metadata expires along with the values it references.
+type TaggedStore struct {
+	s    *Store
+	tags []string
+}
+
+// Tags returns a TaggedStore that tags what it writes with names.
+//
+//	store.Tags("user:42", "posts").Put("posts:42:recent", posts, time.Hour)
+//	store.Tags("posts").Flush()
+func (s *Store) Tags(names ...string) *TaggedStore {
+	tags := make([]string, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name != "" && !seen[name] {
+			seen[name] = true
+			tags = append(tags, name)
+		}
+	}
+	return &TaggedStore{s: s, tags: tags}
+}
+
+// Set stores val under key like Store's Set.
+func (t *TaggedStore) Set(key string, val interface{}, expires ...int) error {
+	return t.s.set(key, val, expires, t.tags)
+}
+
+// Put stores val under key like Store's Put.
+func (t *TaggedStore) Put(key string, val interface{}, ttl time.Duration) error {
+	return t.SetMany(map[string]interface{}{key: val}, ttl)
+}
+
+// SetMany stores every value like Store's SetMany.
+func (t *TaggedStore) SetMany(values map[string]interface{}, ttl time.Duration) error {
+	return t.s.setMany(values, ttl, t.tags)
+}
+
+// Remember works like Store's Remember, tagging the value fn returns.
+func (t *TaggedStore) Remember(key string, ttl time.Duration, dst interface{}, fn func() (interface{}, error)) error {
+	return t.s.remember(key, ttl, dst, fn, t.tags)
+}
+
+// Flush removes every key written under any of the tags, and the tags
+// themselves.
+func (t *TaggedStore) Flush() error {
+	if len(t.tags) == 0 {
+		return nil
+	}
+	return t.s.d.flushTags(t.tags)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"myapp/data"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/lozhkindm/celeritas"
+)
+
+const usage = `Usage: %s <command> [arguments]
+
+Commands:
+  serve                          start the web server (default)
+  migrate up                     apply all pending migrations
+  migrate down [all]             roll back the last migration, or all of them
+  migrate steps N                apply N migrations, or roll back -N
+  migrate force                  reset the version after a failed migration
+  migrate status                 show the current migration version
+  make migration|handler|model|mail|middleware <name>
+                                 generate code with its test and wiring
+  make auth                      create the migrations for users and logins
+  key:generate                   print a new 32 character encryption key
+  key:rotate                     encrypt stored data again with the current KEY
+  cache:clear                    remove every entry from the cache
+  role:assign <email> <role>     give a user a role
+  role:grant <role> <permission> give a role a permission (* for all)
+  routes:list                    list the registered routes
+`
+
+// run dispatches the command line. Commands that only write files do not
+// need, and so do not open, the database or cache.
+func run(args []string) error {
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
+
+	switch args[0] {
+	case "serve":
+		return initApplication().serve()
+	case "migrate":
+		return withApplication(func(a *application) error {
+			return a.migrate(args[1:])
+		})
+	case "make":
+		path, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		return makeCommand(path, args[1:])
+	case "key:generate":
+		fmt.Println((&celeritas.Celeritas{}).RandStr(32))
+		return nil
+	case "key:rotate":
+		return withApplication(func(a *application) error {
+			n, err := data.ReEncrypt(a.Encrypter.Reencrypt)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Encrypted %d values again with the current key\n", n)
+			return nil
+		})
+	case "cache:clear":
+		return withApplication(func(a *application) error {
+			if a.App.Cache == nil {
+				return errors.New("no cache is configured")
+			}
+			if a.Config.Cache == "memory" {
+				return errors.New("the memory cache lives in the server process; restart the server to clear it")
+			}
+			if err := a.App.Cache.Empty(); err != nil {
+				return err
+			}
+			fmt.Println("Cache cleared")
+			return nil
+		})
+	case "role:assign":
+		if len(args) < 3 {
+			return errors.New("role:assign needs an email and a role")
+		}
+		return withApplication(func(a *application) error {
+			user, err := a.Models.Users.GetByEmail(args[1])
+			if err != nil {
+				return fmt.Errorf("no user with email %s: %w", args[1], err)
+			}
+			if err := a.Models.Roles.Assign(user.ID, args[2]); err != nil {
+				return err
+			}
+			fmt.Printf("%s now has the %s role\n", user.Email, args[2])
+			return nil
+		})
+	case "role:grant":
+		if len(args) < 3 {
+			return errors.New("role:grant needs a role and a permission")
+		}
+		return withApplication(func(a *application) error {
+			if err := a.Models.Roles.Grant(args[1], args[2]); err != nil {
+				return err
+			}
+			fmt.Printf("The %s role now has the %s permission\n", args[1], args[2])
+			return nil
+		})
+	case "routes:list":
+		return withApplication(func(a *application) error {
+			walk := func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+				fmt.Printf("%-7s %s\n", method, route)
+				return nil
+			}
+			if err := chi.Walk(a.App.Routes, walk); err != nil {
+				return err
+			}
+			return chi.Walk(a.API, walk)
+		})
+	case "help", "-h", "--help":
+		fmt.Printf(usage, os.Args[0])
+		return nil
+	default:
+		fmt.Fprintf(os.Stderr, usage, os.Args[0])
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// withApplication runs fn against a fully initialised application and then
+// releases everything it opened.
+func withApplication(fn func(a *application) error) error {
+	a := initApplication()
+	err := fn(a)
+	if shutdownErr := a.shutdown(); err == nil {
+		err = shutdownErr
+	}
+	return err
+}
+
+func (a *application) migrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New("migrate needs one of: up, down, steps, force, status")
+	}
+	if a.Config.Database.Type == "" {
+		return errors.New("DATABASE_TYPE is not set")
+	}
+
+	dsn, err := migrationURL(a.App, a.Config.Database)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		err = a.App.MigrateUp(dsn)
+	case "down":
+		if len(args) > 1 && args[1] == "all" {
+			err = a.App.MigrateDownAll(dsn)
+		} else {
+			err = a.App.MigrateSteps(-1, dsn)
+		}
+	case "steps":
+		if len(args) < 2 {
+			return errors.New("migrate steps needs a number of steps")
+		}
+		n, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return fmt.Errorf("invalid number of steps %q", args[1])
+		}
+		err = a.App.MigrateSteps(n, dsn)
+	case "force":
+		err = a.App.MigrateForce(dsn)
+	case "status":
+		return a.migrationStatus(dsn)
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+
+	if errors.Is(err, migrate.ErrNoChange) {
+		fmt.Println("Nothing to migrate")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Migrate %s done\n", strings.Join(args, " "))
+	return nil
+}
+
+func (a *application) migrationStatus(dsn string) error {
+	m, err := migrate.New(fmt.Sprintf("file://%s/migrations", a.App.RootPath), dsn)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = m.Close()
+	}()
+
+	version, dirty, err := m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		fmt.Println("No migrations applied")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if dirty {
+		fmt.Printf("Version %d (dirty: fix the database and run migrate force)\n", version)
+	} else {
+		fmt.Printf("Version %d\n", version)
+	}
+	return nil
+}
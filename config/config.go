@@ -0,0 +1,202 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Config is every setting the skeleton reads from .env and the environment.
+type Config struct {
+	Env      string   `env:"APP_ENV" default:"development"`
+	AppName  string   `env:"APP_NAME" default:"myapp"`
+	Locale   string   `env:"APP_LOCALE" default:"en"`
+	Debug    bool     `env:"DEBUG"`
+	Key      string   `env:"KEY" required:"true"`
+	OldKeys  []string `env:"OLD_KEYS"`
+	Renderer string   `env:"RENDERER" default:"jet" oneof:"go jet"`
+	Cache    string   `env:"CACHE" oneof:"redis badger memory"`
+
+	Server      ServerConfig
+	TLS         TLSConfig
+	Database    DatabaseConfig
+	Redis       RedisConfig
+	MemoryCache MemoryCacheConfig
+	Cookie      CookieConfig
+	Session     SessionConfig
+	Mail        MailConfig
+	OAuth       OAuthConfig
+}
+
+type ServerConfig struct {
+	Name            string        `env:"SERVER_NAME" default:"localhost"`
+	Port            int           `env:"PORT" default:"4000"`
+	URL             string        `env:"APP_URL" default:"http://localhost:4000"`
+	Secure          bool          `env:"SECURE"`
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" default:"30"`
+}
+
+type TLSConfig struct {
+	CertFile         string `env:"TLS_CERT_FILE"`
+	KeyFile          string `env:"TLS_KEY_FILE"`
+	SelfSigned       bool   `env:"TLS_SELF_SIGNED"`
+	HTTPRedirectPort int    `env:"HTTP_REDIRECT_PORT"`
+}
+
+type DatabaseConfig struct {
+	Type                string        `env:"DATABASE_TYPE" oneof:"postgres postgresql mysql mariadb sqlite sqlite3"`
+	Host                string        `env:"DATABASE_HOST"`
+	Port                int           `env:"DATABASE_PORT"`
+	User                string        `env:"DATABASE_USER"`
+	Password            string        `env:"DATABASE_PASS"`
+	Name                string        `env:"DATABASE_NAME"`
+	SSLMode             string        `env:"DATABASE_SSL_MODE" default:"disable"`
+	Collation           string        `env:"DATABASE_COLLATION" default:"utf8mb4_unicode_ci"`
+	MaxOpenConns        int           `env:"DATABASE_MAX_OPEN_CONNS" default:"25"`
+	MaxIdleConns        int           `env:"DATABASE_MAX_IDLE_CONNS" default:"25"`
+	ConnMaxLifetime     time.Duration `env:"DATABASE_CONN_MAX_LIFETIME" default:"300"`
+	ConnectRetries      int           `env:"DATABASE_CONNECT_RETRIES" default:"5"`
+	HealthCheckInterval time.Duration `env:"DATABASE_HEALTH_CHECK_INTERVAL" default:"30"`
+	ReplicaDSNs         []string      `env:"DATABASE_REPLICA_DSNS"`
+}
+
+type RedisConfig struct {
+	Host     string `env:"REDIS_HOST"`
+	Password string `env:"REDIS_PASSWORD"`
+	Prefix   string `env:"REDIS_PREFIX" default:"celeritas"`
+}
+
+type MemoryCacheConfig struct {
+	MaxEntries      int           `env:"CACHE_MAX_ENTRIES" default:"10000"`
+	MaxBytes        int           `env:"CACHE_MAX_BYTES" default:"67108864"`
+	JanitorInterval time.Duration `env:"CACHE_JANITOR_INTERVAL" default:"60"`
+}
+
+type CookieConfig struct {
+	Name     string `env:"COOKIE_NAME" default:"celeritas"`
+	Lifetime int    `env:"COOKIE_LIFETIME" default:"1440"`
+	Persist  bool   `env:"COOKIE_PERSIST" default:"true"`
+	Secure   bool   `env:"COOKIE_SECURE" default:"false"`
+	Domain   string `env:"COOKIE_DOMAIN" default:"localhost"`
+}
+
+type SessionConfig struct {
+	Type string `env:"SESSION_TYPE" default:"cookie" oneof:"cookie redis mysql mariadb postgres postgresql sqlite sqlite3"`
+}
+
+type MailConfig struct {
+	Domain      string `env:"MAIL_DOMAIN"`
+	Host        string `env:"SMTP_HOST" default:"localhost"`
+	Port        int    `env:"SMTP_PORT" default:"1025"`
+	Username    string `env:"SMTP_USERNAME"`
+	Password    string `env:"SMTP_PASSWORD"`
+	Encryption  string `env:"SMTP_ENCRYPTION" default:"none" oneof:"none tls ssl"`
+	FromName    string `env:"FROM_NAME"`
+	FromAddress string `env:"FROM_ADDRESS"`
+	API         string `env:"MAILER_API" oneof:"smtp mailgun sparkpost sendgrid"`
+	APIKey      string `env:"MAILER_KEY"`
+	APIURL      string `env:"MAILER_URL"`
+}
+
+// OAuthConfig holds the identity providers users can log in with. A provider
+// is turned on by setting its client ID.
+type OAuthConfig struct {
+	GitHubClientID     string `env:"GITHUB_CLIENT_ID"`
+	GitHubClientSecret string `env:"GITHUB_CLIENT_SECRET"`
+	GoogleClientID     string `env:"GOOGLE_CLIENT_ID"`
+	GoogleClientSecret string `env:"GOOGLE_CLIENT_SECRET"`
+	OIDCName           string `env:"OIDC_NAME" default:"sso"`
+	OIDCIssuer         string `env:"OIDC_ISSUER"`
+	OIDCClientID       string `env:"OIDC_CLIENT_ID"`
+	OIDCClientSecret   string `env:"OIDC_CLIENT_SECRET"`
+}
+
+// New loads the dotenv files in rootPath and returns the validated Config. It
+// refuses to run in production with the KEY from .env.example.
+func New(rootPath string) (*Config, error) {
+	if err := LoadEnv(rootPath); err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{}
+	if err := Load(cfg); err != nil {
+		return nil, err
+	}
+
+	if cfg.IsProduction() && cfg.Key != "" && cfg.Key == exampleKey(rootPath) {
+		return nil, errors.New("KEY is still the value from .env.example; generate a new key before running in production")
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+func (c *Config) Validate() error {
+	var errs Errors
+
+	if c.Key != "" && len(c.Key) != 32 {
+		errs = append(errs, fmt.Errorf("KEY must be exactly 32 characters long, got %d", len(c.Key)))
+	}
+	for i, key := range c.OldKeys {
+		if len(key) != 32 {
+			errs = append(errs, fmt.Errorf("OLD_KEYS entry %d must be exactly 32 characters long, got %d", i+1, len(key)))
+		}
+	}
+
+	switch c.Database.Type {
+	case "postgres", "postgresql", "mysql", "mariadb":
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("DATABASE_HOST is required for a server database"))
+		}
+		if c.Database.Port == 0 {
+			errs = append(errs, errors.New("DATABASE_PORT is required for a server database"))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("DATABASE_NAME is required for a server database"))
+		}
+	}
+
+	if c.Database.Type != "" && c.Database.HealthCheckInterval <= 0 {
+		errs = append(errs, errors.New("DATABASE_HEALTH_CHECK_INTERVAL must be greater than zero"))
+	}
+
+	switch c.Session.Type {
+	case "mysql", "mariadb", "postgres", "postgresql", "sqlite", "sqlite3":
+		if c.Database.Type == "" {
+			errs = append(errs, fmt.Errorf("SESSION_TYPE %s needs DATABASE_TYPE to be set", c.Session.Type))
+		}
+	case "redis":
+		if c.Redis.Host == "" {
+			errs = append(errs, errors.New("REDIS_HOST is required for redis sessions"))
+		}
+	}
+
+	if c.Cache == "redis" && c.Redis.Host == "" {
+		errs = append(errs, errors.New("REDIS_HOST is required for the redis cache"))
+	}
+	if c.MemoryCache.MaxEntries < 0 || c.MemoryCache.MaxBytes < 0 {
+		errs = append(errs, errors.New("CACHE_MAX_ENTRIES and CACHE_MAX_BYTES must not be negative"))
+	}
+
+	if c.Mail.API != "" && c.Mail.API != "smtp" && (c.Mail.APIKey == "" || c.Mail.APIURL == "") {
+		errs = append(errs, fmt.Errorf("MAILER_KEY and MAILER_URL are required for MAILER_API %s", c.Mail.API))
+	}
+
+	if c.OAuth.GitHubClientID != "" && c.OAuth.GitHubClientSecret == "" {
+		errs = append(errs, errors.New("GITHUB_CLIENT_SECRET is required when GITHUB_CLIENT_ID is set"))
+	}
+	if c.OAuth.GoogleClientID != "" && c.OAuth.GoogleClientSecret == "" {
+		errs = append(errs, errors.New("GOOGLE_CLIENT_SECRET is required when GOOGLE_CLIENT_ID is set"))
+	}
+	if c.OAuth.OIDCClientID != "" && (c.OAuth.OIDCClientSecret == "" || c.OAuth.OIDCIssuer == "") {
+		errs = append(errs, errors.New("OIDC_ISSUER and OIDC_CLIENT_SECRET are required when OIDC_CLIENT_ID is set"))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+const defaultAppEnv = "development"
+
+// LoadEnv loads the dotenv files in rootPath into the environment, from lowest
+// to highest precedence:
+//
+//	.env
+//	.env.<APP_ENV>
+//	.env.local (skipped when APP_ENV is test)
+//
+// Variables already present in the real environment always win. APP_ENV
+// itself may come from the environment, .env.local or .env, and defaults to
+// development. A missing .env is an error unless KEY (or KEY_FILE) is already
+// set, which is how container deployments are configured.
+func LoadEnv(rootPath string) error {
+	base, err := readEnvFile(filepath.Join(rootPath, ".env"))
+	if errors.Is(err, os.ErrNotExist) {
+		if os.Getenv("KEY") == "" && os.Getenv("KEY_FILE") == "" {
+			return fmt.Errorf("no .env found in %s: copy .env.example to .env or set the environment", rootPath)
+		}
+		base = map[string]string{}
+	} else if err != nil {
+		return err
+	}
+
+	local, err := readEnvFile(filepath.Join(rootPath, ".env.local"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	appEnv := firstNonEmpty(os.Getenv("APP_ENV"), local["APP_ENV"], base["APP_ENV"], defaultAppEnv)
+
+	layers := []map[string]string{base}
+	envSpecific, err := readEnvFile(filepath.Join(rootPath, fmt.Sprintf(".env.%s", appEnv)))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	layers = append(layers, envSpecific)
+	if appEnv != "test" {
+		layers = append(layers, local)
+	}
+
+	merged := map[string]string{"APP_ENV": appEnv}
+	for _, layer := range layers {
+		for k, v := range layer {
+			merged[k] = v
+		}
+	}
+
+	for k, v := range merged {
+		if _, ok := os.LookupEnv(k); !ok {
+			_ = os.Setenv(k, v)
+		}
+	}
+	return nil
+}
+
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	return godotenv.Parse(f)
+}
+
+// readSecretFile returns the contents of a mounted secret without the
+// trailing newline most tools add.
+func readSecretFile(path string) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// exampleKey returns the KEY shipped in .env.example, if there is one.
+func exampleKey(rootPath string) string {
+	example, err := readEnvFile(filepath.Join(rootPath, ".env.example"))
+	if err != nil {
+		return ""
+	}
+	return example["KEY"]
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
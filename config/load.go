@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors lists every invalid setting found while loading a configuration.
+type Errors []error
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("invalid configuration:\n  %s", strings.Join(msgs, "\n  "))
+}
+
+// Validator is implemented by configuration structs that need checks spanning
+// several fields. Load calls it after every field has been populated. The
+// Validate method of an embedded struct is promoted rather than called
+// separately, so a struct that embeds Config and declares its own Validate
+// should call Config.Validate from it.
+type Validator interface {
+	Validate() error
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// Load populates the struct pointed to by dst from the environment. Fields are
+// mapped with the following tags:
+//
+//	env:"NAME"         the variable to read
+//	default:"value"    used when the variable is unset or empty
+//	required:"true"    the variable must have a value
+//	oneof:"a b c"      the value must be one of the listed words, in any
+//	                   case; it is stored in lower case
+//
+// When NAME is empty but NAME_FILE is set, the value is read from that file,
+// which is how Docker and Kubernetes mount secrets.
+// Nested and embedded structs are walked recursively, so an application can
+// extend Config by embedding it in its own struct. Defaults are written back
+// to the environment so code that still reads os.Getenv sees the same values.
+// All problems are returned together as Errors.
+func Load(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: Load needs a pointer to a struct, got %T", dst)
+	}
+
+	var errs Errors
+	load(v.Elem(), true, &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func load(v reflect.Value, validate bool, errs *Errors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		key, ok := field.Tag.Lookup("env")
+		if !ok {
+			if fv.Kind() == reflect.Struct && fv.Type() != durationType {
+				load(fv, !field.Anonymous, errs)
+			}
+			continue
+		}
+
+		raw := strings.TrimSpace(os.Getenv(key))
+		if raw == "" {
+			if path := os.Getenv(fmt.Sprintf("%s_FILE", key)); path != "" {
+				secret, err := readSecretFile(path)
+				if err != nil {
+					*errs = append(*errs, fmt.Errorf("%s_FILE: %s", key, err))
+					continue
+				}
+				raw = secret
+				_ = os.Setenv(key, secret)
+			}
+		}
+		if raw == "" {
+			if def, ok := field.Tag.Lookup("default"); ok && def != "" {
+				raw = def
+				_ = os.Setenv(key, def)
+			}
+		}
+
+		if raw == "" {
+			if field.Tag.Get("required") == "true" {
+				*errs = append(*errs, fmt.Errorf("%s is required", key))
+			}
+			continue
+		}
+
+		if oneof := field.Tag.Get("oneof"); oneof != "" {
+			if !contains(strings.Fields(oneof), strings.ToLower(raw)) {
+				*errs = append(*errs, fmt.Errorf("%s must be one of [%s], got %q", key, oneof, raw))
+				continue
+			}
+			raw = strings.ToLower(raw)
+			_ = os.Setenv(key, raw)
+		}
+
+		if err := set(fv, raw); err != nil {
+			*errs = append(*errs, fmt.Errorf("%s: %s", key, err))
+		}
+	}
+
+	if validate && v.CanAddr() {
+		if val, ok := v.Addr().Interface().(Validator); ok {
+			if err := val.Validate(); err != nil {
+				if many, ok := err.(Errors); ok {
+					*errs = append(*errs, many...)
+				} else {
+					*errs = append(*errs, err)
+				}
+			}
+		}
+	}
+}
+
+func set(fv reflect.Value, raw string) error {
+	if fv.Type() == durationType {
+		d, err := parseDuration(raw)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(int64(d))
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a positive integer", raw)
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, fv.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported slice type %s", fv.Type())
+		}
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		fv.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported field type %s", fv.Type())
+	}
+	return nil
+}
+
+// parseDuration accepts a Go duration ("90s", "5m") or a plain number of
+// seconds.
+func parseDuration(raw string) (time.Duration, error) {
+	if n, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(n) * time.Second, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a duration", raw)
+	}
+	return d, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setenv sets the variables for the rest of the test. An empty value stands
+// for an unset variable, which Load treats the same way.
+func setenv(t *testing.T, vars map[string]string) {
+	t.Helper()
+	for k, v := range vars {
+		t.Setenv(k, v)
+	}
+}
+
+func TestLoadStoresOneOfInLowerCase(t *testing.T) {
+	setenv(t, map[string]string{"CACHE": "Memory", "DATABASE_TYPE": "MySQL"})
+
+	var dst struct {
+		Cache    string `env:"CACHE" oneof:"redis badger memory"`
+		Database string `env:"DATABASE_TYPE" oneof:"postgres mysql sqlite"`
+	}
+	if err := Load(&dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Cache != "memory" || dst.Database != "mysql" {
+		t.Errorf("expected memory and mysql, got %s and %s", dst.Cache, dst.Database)
+	}
+	if got := os.Getenv("DATABASE_TYPE"); got != "mysql" {
+		t.Errorf("expected DATABASE_TYPE to be written back as mysql, got %s", got)
+	}
+}
+
+func TestLoadRejectsUnlistedValue(t *testing.T) {
+	setenv(t, map[string]string{"CACHE": "memcached"})
+
+	var dst struct {
+		Cache string `env:"CACHE" oneof:"redis badger memory"`
+	}
+	err := Load(&dst)
+	if err == nil || !strings.Contains(err.Error(), `CACHE must be one of [redis badger memory], got "memcached"`) {
+		t.Errorf("expected memcached to be refused, got %v", err)
+	}
+}
+
+func TestLoadDefaults(t *testing.T) {
+	setenv(t, map[string]string{"TEST_NAME": "", "TEST_PORT": "", "TEST_TIMEOUT": "", "TEST_INTERVAL": "90s"})
+
+	var dst struct {
+		Name     string        `env:"TEST_NAME" default:"myapp"`
+		Port     int           `env:"TEST_PORT" default:"4000"`
+		Timeout  time.Duration `env:"TEST_TIMEOUT" default:"30"`
+		Interval time.Duration `env:"TEST_INTERVAL" default:"30"`
+	}
+	if err := Load(&dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Name != "myapp" || dst.Port != 4000 {
+		t.Errorf("expected the defaults myapp and 4000, got %s and %d", dst.Name, dst.Port)
+	}
+	if dst.Timeout != 30*time.Second {
+		t.Errorf("expected a plain number to be read as seconds, got %s", dst.Timeout)
+	}
+	if dst.Interval != 90*time.Second {
+		t.Errorf("expected a set variable to win over the default, got %s", dst.Interval)
+	}
+	if got := os.Getenv("TEST_PORT"); got != "4000" {
+		t.Errorf("expected the default to be written back to the environment, got %q", got)
+	}
+}
+
+func TestLoadRequired(t *testing.T) {
+	setenv(t, map[string]string{"TEST_KEY": "", "TEST_KEY_FILE": ""})
+
+	var dst struct {
+		Key string `env:"TEST_KEY" required:"true"`
+	}
+	if err := Load(&dst); err == nil || !strings.Contains(err.Error(), "TEST_KEY is required") {
+		t.Errorf("expected TEST_KEY to be required, got %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(path, []byte("from a file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_KEY_FILE", path)
+	if err := Load(&dst); err != nil {
+		t.Fatal(err)
+	}
+	if dst.Key != "from a file" {
+		t.Errorf("expected the key to be read from TEST_KEY_FILE, got %q", dst.Key)
+	}
+}
+
+// ValidatedConfig stands in for an application config that checks several
+// fields at once.
+type ValidatedConfig struct {
+	Port int `env:"TEST_PORT"`
+}
+
+func (c *ValidatedConfig) Validate() error {
+	return Errors{errors.New("first check"), errors.New("second check")}
+}
+
+func TestLoadCollectsErrors(t *testing.T) {
+	setenv(t, map[string]string{"TEST_PORT": "http", "TEST_MODE": "fast", "TEST_KEY": "", "TEST_KEY_FILE": ""})
+
+	var dst struct {
+		ValidatedConfig
+		Mode  string `env:"TEST_MODE" oneof:"slow safe"`
+		Key   string `env:"TEST_KEY" required:"true"`
+		Inner struct {
+			Debug bool `env:"TEST_PORT"`
+		}
+	}
+	err := Load(&dst)
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+	want := []string{
+		`TEST_PORT: "http" is not an integer`,
+		`TEST_MODE must be one of [slow safe], got "fast"`,
+		"TEST_KEY is required",
+		`TEST_PORT: "http" is not a boolean`,
+		"first check",
+		"second check",
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %d: %v", len(want), len(errs), err)
+	}
+	for i, w := range want {
+		if errs[i].Error() != w {
+			t.Errorf("expected error %d to be %q, got %q", i, w, errs[i])
+		}
+	}
+	if !strings.HasPrefix(err.Error(), "invalid configuration:\n  ") {
+		t.Errorf("expected every error in one message, got %q", err)
+	}
+}
+
+func TestConfigValidateCollectsErrors(t *testing.T) {
+	cfg := Config{
+		Key:      "too short",
+		Database: DatabaseConfig{Type: "postgres"},
+		Cache:    "redis",
+	}
+	err := cfg.Validate()
+
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected Errors, got %v", err)
+	}
+	for _, want := range []string{
+		"KEY must be exactly 32 characters long",
+		"DATABASE_HOST is required",
+		"DATABASE_PORT is required",
+		"DATABASE_NAME is required",
+		"DATABASE_HEALTH_CHECK_INTERVAL must be greater than zero",
+		"REDIS_HOST is required for the redis cache",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q among the errors, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+// Package cookies stores small values in the browser without letting the
+// user read or change them: signed cookies can be read but not altered,
+// encrypted cookies can be neither. Both carry their expiry inside the value,
+// so an old cookie replayed after it should have gone is still refused.
+package cookies
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"myapp/encryption"
+)
+
+var (
+	ErrInvalid = errors.New("cookies: cookie was altered or not made by this application")
+	ErrExpired = errors.New("cookies: cookie has expired")
+)
+
+// Jar writes and reads protected cookies. Signed cookies use Secret, encrypted
+// ones Encrypter, so they survive a key rotation while the old key is kept.
+type Jar struct {
+	Secret    []byte
+	Encrypter *encryption.Encrypter
+	Domain    string
+	Secure    bool
+	// Now returns the current time; nil means time.Now.
+	Now func() time.Time
+}
+
+// SetSigned writes a cookie whose value is readable by the user but cannot be
+// changed. It is valid for ttl.
+func (j *Jar) SetSigned(w http.ResponseWriter, name, value string, ttl time.Duration) {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(j.expiry(ttl) + "|" + value))
+	j.set(w, name, payload+"."+j.sign(name, payload), ttl)
+}
+
+// GetSigned returns the value of a signed cookie. The error is
+// http.ErrNoCookie when there is none, ErrInvalid when it was altered and
+// ErrExpired when it is too old.
+func (j *Jar) GetSigned(r *http.Request, name string) (string, error) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+
+	i := strings.LastIndex(c.Value, ".")
+	if i < 0 {
+		return "", ErrInvalid
+	}
+	payload, sig := c.Value[:i], c.Value[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(j.sign(name, payload))) {
+		return "", ErrInvalid
+	}
+	b, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	return j.open(string(b))
+}
+
+// SetEncrypted writes a cookie whose value the user can neither read nor
+// change. It is valid for ttl.
+func (j *Jar) SetEncrypted(w http.ResponseWriter, name, value string, ttl time.Duration) error {
+	// the name is encrypted with the value so the cookie cannot be renamed
+	encrypted, err := j.Encrypter.Encrypt(name + "|" + j.expiry(ttl) + "|" + value)
+	if err != nil {
+		return err
+	}
+	j.set(w, name, encrypted, ttl)
+	return nil
+}
+
+// GetEncrypted returns the value of an encrypted cookie, with the same errors
+// as GetSigned.
+func (j *Jar) GetEncrypted(r *http.Request, name string) (string, error) {
+	c, err := r.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+
+	text, err := j.Encrypter.Decrypt(c.Value)
+	if err != nil || !strings.HasPrefix(text, name+"|") {
+		return "", ErrInvalid
+	}
+	return j.open(strings.TrimPrefix(text, name+"|"))
+}
+
+// Delete tells the browser to remove a cookie.
+func (j *Jar) Delete(w http.ResponseWriter, name string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		Domain:   j.Domain,
+		Expires:  time.Unix(1, 0),
+		MaxAge:   -1,
+		Secure:   j.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (j *Jar) set(w http.ResponseWriter, name, value string, ttl time.Duration) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   j.Domain,
+		Expires:  j.now().Add(ttl),
+		MaxAge:   int(ttl.Seconds()),
+		Secure:   j.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (j *Jar) sign(name, payload string) string {
+	mac := hmac.New(sha256.New, j.Secret)
+	mac.Write([]byte("cookie:" + name + "|" + payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (j *Jar) expiry(ttl time.Duration) string {
+	return strconv.FormatInt(j.now().Add(ttl).Unix(), 10)
+}
+
+// open splits an expiry|value payload and checks the expiry.
+func (j *Jar) open(payload string) (string, error) {
+	parts := strings.SplitN(payload, "|", 2)
+	if len(parts) != 2 {
+		return "", ErrInvalid
+	}
+	expires, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "", ErrInvalid
+	}
+	if j.now().Unix() >= expires {
+		return "", ErrExpired
+	}
+	return parts[1], nil
+}
+
+func (j *Jar) now() time.Time {
+	if j.Now != nil {
+		return j.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,12 @@
+package data
+
+import "myapp/cache"
+
+// appCache is optional; models that use it must work without it.
+var appCache *cache.Store
+
+// UseCache lets models cache lookups that are read far more often than they
+// change, such as the permissions of a user.
+func UseCache(c *cache.Store) {
+	appCache = c
+}
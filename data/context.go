@@ -0,0 +1,33 @@
+package data
+
+import "context"
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	tokenKey
+)
+
+// WithUser returns a copy of ctx carrying the authenticated user.
+func WithUser(ctx context.Context, user *User) context.Context {
+	return context.WithValue(ctx, userKey, user)
+}
+
+// UserFromContext returns the user stored by WithUser, or nil.
+func UserFromContext(ctx context.Context) *User {
+	user, _ := ctx.Value(userKey).(*User)
+	return user
+}
+
+// WithToken returns a copy of ctx carrying the API token the request was
+// authenticated with.
+func WithToken(ctx context.Context, token *Token) context.Context {
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+// TokenFromContext returns the token stored by WithToken, or nil.
+func TokenFromContext(ctx context.Context) *Token {
+	token, _ := ctx.Value(tokenKey).(*Token)
+	return token
+}
@@ -0,0 +1,70 @@
+package data
+
+import (
+	"fmt"
+
+	"myapp/totp"
+)
+
+// EncryptedColumn is a column whose values are encrypted with the
+// application key. Valid reports whether a decrypted value has the form the
+// column's values have; it picks the key of a legacy value, which does not
+// record it.
+type EncryptedColumn struct {
+	Table  string
+	Column string
+	Valid  func(text string) bool
+}
+
+// EncryptedColumns are the columns key:rotate encrypts again with the
+// current key. Add to it when a model stores encrypted values.
+var EncryptedColumns = []EncryptedColumn{
+	{Table: "two_factors", Column: "secret", Valid: validTOTPSecret},
+}
+
+// validTOTPSecret accepts base32 secrets of at least the 128 bits RFC 4226
+// asks for; totp.GenerateSecret makes 160 bit ones.
+func validTOTPSecret(text string) bool {
+	t, err := totp.New(text)
+	return err == nil && len(t.Secret) >= 16
+}
+
+// ReEncrypt passes every value in EncryptedColumns to reencrypt, with the
+// column's Valid, and stores the result when it reports a change. A value
+// changed by someone else in the meantime is left alone. It returns how many
+// values were updated.
+func ReEncrypt(reencrypt func(ciphertext string, valid func(string) bool) (string, bool, error)) (int, error) {
+	updated := 0
+	for _, col := range EncryptedColumns {
+		var rows []struct {
+			ID    int    `db:"id"`
+			Value string `db:"value"`
+		}
+		err := upper.SQL().Select("id", col.Column+" AS value").From(col.Table).All(&rows)
+		if err != nil {
+			return updated, err
+		}
+
+		for _, row := range rows {
+			value, changed, err := reencrypt(row.Value, col.Valid)
+			if err != nil {
+				return updated, fmt.Errorf("%s.%s id %d: %w", col.Table, col.Column, row.ID, err)
+			}
+			if !changed {
+				continue
+			}
+			res, err := upper.SQL().Update(col.Table).
+				Set(col.Column, value).
+				Where("id = ?", row.ID).
+				And(col.Column+" = ?", row.Value).
+				Exec()
+			if err != nil {
+				return updated, err
+			}
+			if n, err := res.RowsAffected(); err == nil && n == 1 {
+				updated++
+			}
+		}
+	}
+	return updated, nil
+}
@@ -0,0 +1,75 @@
+package data
+
+import (
+	"testing"
+
+	"myapp/encryption"
+	"myapp/totp"
+
+	"github.com/lozhkindm/celeritas"
+)
+
+func TestValidTOTPSecret(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for text, want := range map[string]bool{
+		secret:                    true,
+		"JBSWY3DPEHPK3PXPJBSWY3DP": false,
+		"":                        false,
+		"not base32 at all!":      false,
+		"\x8f\x01garbage\xff":     false,
+	} {
+		if got := validTOTPSecret(text); got != want {
+			t.Errorf("%q: expected %v, got %v", text, want, got)
+		}
+	}
+}
+
+func TestReEncryptMigratesLegacySecrets(t *testing.T) {
+	current := []byte("0123456789abcdef0123456789abcdef")
+	old := []byte("fedcba9876543210fedcba9876543210")
+	e, err := encryption.New(current, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacy, err := (&celeritas.Encryption{Key: old}).Encrypt(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var u User
+	userID, err := u.Insert(User{FirstName: "Legacy", LastName: "User", Email: "legacy@example.com", Password: "password", Active: 1})
+	if err != nil {
+		t.Fatal("insert:", err)
+	}
+	defer func() {
+		_ = u.Delete(userID)
+	}()
+	var tf TwoFactor
+	if err := tf.Begin(userID, legacy); err != nil {
+		t.Fatal("begin:", err)
+	}
+
+	n, err := ReEncrypt(e.Reencrypt)
+	if err != nil || n != 1 {
+		t.Fatalf("expected one value to be encrypted again, got %d, %v", n, err)
+	}
+	one, err := tf.GetForUser(userID)
+	if err != nil {
+		t.Fatal("get:", err)
+	}
+	if text, err := e.Decrypt(one.Secret); err != nil || text != secret {
+		t.Errorf("expected the secret after ReEncrypt, got %q, %v", text, err)
+	}
+
+	if n, err := ReEncrypt(e.Reencrypt); err != nil || n != 0 {
+		t.Errorf("expected nothing left to encrypt again, got %d, %v", n, err)
+	}
+}
@@ -0,0 +1,37 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	udb "github.com/upper/db/v4"
+)
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Lookup answers the unique and exists validation rules. It reads from the
+// primary, so a value written a moment ago is seen.
+type Lookup struct{}
+
+// Count returns how many rows of table have value in column, not counting
+// the row with id exceptID when it is not 0. table and column come from code,
+// not input, but are still checked since they cannot be bound as parameters.
+func (Lookup) Count(table, column string, value interface{}, exceptID int) (int, error) {
+	if upper == nil {
+		return 0, errors.New("no database is configured")
+	}
+	if !identifier.MatchString(table) || !identifier.MatchString(column) {
+		return 0, fmt.Errorf("invalid table or column %s.%s", table, column)
+	}
+
+	cond := udb.Cond{column: value}
+	if exceptID != 0 {
+		cond["id !="] = exceptID
+	}
+	n, err := upper.Collection(table).Find(cond).Count()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
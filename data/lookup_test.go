@@ -0,0 +1,25 @@
+package data
+
+import "testing"
+
+func TestLookupCount(t *testing.T) {
+	var u User
+	id, err := u.Insert(User{FirstName: "Grace", LastName: "Hopper", Email: "grace@example.com", Password: "secret"})
+	if err != nil {
+		t.Fatal("insert:", err)
+	}
+	defer func() {
+		_ = u.Delete(id)
+	}()
+
+	var l Lookup
+	if n, err := l.Count("users", "email", "grace@example.com", 0); err != nil || n != 1 {
+		t.Errorf("expected 1 row, got %d, %v", n, err)
+	}
+	if n, err := l.Count("users", "email", "grace@example.com", id); err != nil || n != 0 {
+		t.Errorf("expected the excepted row not to count, got %d, %v", n, err)
+	}
+	if _, err := l.Count("users; DROP TABLE users", "email", "x", 0); err == nil {
+		t.Error("expected an error for an invalid table name")
+	}
+}
@@ -3,31 +3,86 @@ package data
 import (
 	"database/sql"
 	"fmt"
-	"os"
+
+	"myapp/database"
 
 	udb "github.com/upper/db/v4"
 	"github.com/upper/db/v4/adapter/mysql"
 	"github.com/upper/db/v4/adapter/postgresql"
+	"github.com/upper/db/v4/adapter/sqlite"
 )
 
 var (
-	db    *sql.DB
-	upper udb.Session
+	db       *sql.DB
+	upper    udb.Session
+	dbs      *database.Database
+	replicas map[*sql.DB]udb.Session
 )
 
-type Models struct{}
+type Models struct {
+	Users          User
+	RememberTokens RememberToken
+	PasswordResets PasswordReset
+	Tokens         Token
+	Roles          Role
+	Permissions    Permission
+	TwoFactors     TwoFactor
+	RecoveryCodes  RecoveryCode
+	UserIdentities UserIdentity
+}
+
+func New(d *database.Database) Models {
+	if d == nil {
+		return Models{}
+	}
+
+	dbs = d
+	db = d.Primary
+	upper = newSession(d.Type, db)
+
+	replicas = make(map[*sql.DB]udb.Session, len(d.Replicas))
+	for _, r := range d.Replicas {
+		replicas[r.Pool] = newSession(d.Type, r.Pool)
+	}
+
+	return Models{
+		Users:          User{},
+		RememberTokens: RememberToken{},
+		PasswordResets: PasswordReset{},
+		Tokens:         Token{},
+		Roles:          Role{},
+		Permissions:    Permission{},
+		TwoFactors:     TwoFactor{},
+		RecoveryCodes:  RecoveryCode{},
+		UserIdentities: UserIdentity{},
+	}
+}
 
-func New(dbPool *sql.DB) Models {
-	db = dbPool
+func newSession(dbType string, pool *sql.DB) udb.Session {
+	var sess udb.Session
 
-	switch os.Getenv("DATABASE_TYPE") {
+	switch dbType {
 	case "mysql", "mariadb":
-		upper, _ = mysql.New(db)
+		sess, _ = mysql.New(pool)
 	case "postgres", "postgresql":
-		upper, _ = postgresql.New(db)
+		sess, _ = postgresql.New(pool)
+	case "sqlite", "sqlite3":
+		sess, _ = sqlite.New(pool)
 	}
 
-	return Models{}
+	return sess
+}
+
+// reader returns a session for read-only queries: a healthy replica when
+// there is one, the primary otherwise. Writes and transactions must use upper.
+func reader() udb.Session {
+	if dbs == nil {
+		return upper
+	}
+	if sess, ok := replicas[dbs.Reader()]; ok {
+		return sess
+	}
+	return upper
 }
 
 func getInsertedID(id udb.ID) int {
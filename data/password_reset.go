@@ -0,0 +1,86 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	udb "github.com/upper/db/v4"
+)
+
+// PasswordResetLifetime is how long a password reset link can be used.
+const PasswordResetLifetime = time.Hour
+
+var ErrInvalidPasswordReset = errors.New("invalid or expired password reset")
+
+// PasswordReset is a pending password reset. As with remember tokens, only a
+// hash of the token is stored.
+type PasswordReset struct {
+	ID        int       `db:"id,omitempty"`
+	UserID    int       `db:"user_id"`
+	Token     string    `db:"token"`
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (p *PasswordReset) Table() string {
+	return "password_resets"
+}
+
+// Insert stores token for userID, replacing any reset the user asked for
+// earlier so only the latest link works.
+func (p *PasswordReset) Insert(userID int, token string) error {
+	return upper.Tx(func(sess udb.Session) error {
+		if err := sess.Collection(p.Table()).Find(udb.Cond{"user_id": userID}).Delete(); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		_, err := sess.Collection(p.Table()).Insert(PasswordReset{
+			UserID:    userID,
+			Token:     hashToken(token),
+			ExpiresAt: now.Add(PasswordResetLifetime),
+			CreatedAt: now,
+		})
+		return err
+	})
+}
+
+// Validate returns the reset for token without using it up, or
+// ErrInvalidPasswordReset.
+func (p *PasswordReset) Validate(token string) (*PasswordReset, error) {
+	var one PasswordReset
+	res := upper.Collection(p.Table()).Find(udb.Cond{"token": hashToken(token)})
+	if err := res.One(&one); err != nil {
+		if errors.Is(err, udb.ErrNoMoreRows) {
+			return nil, ErrInvalidPasswordReset
+		}
+		return nil, err
+	}
+
+	if time.Now().After(one.ExpiresAt) {
+		return nil, ErrInvalidPasswordReset
+	}
+	return &one, nil
+}
+
+// Consume uses up token and returns the ID of the user it was issued to. Of
+// two requests racing with the same token only one succeeds.
+func (p *PasswordReset) Consume(token string) (int, error) {
+	one, err := p.Validate(token)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := upper.SQL().Exec("DELETE FROM password_resets WHERE id = ?", one.ID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n != 1 {
+		return 0, ErrInvalidPasswordReset
+	}
+	return one.UserID, nil
+}
@@ -0,0 +1,137 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	udb "github.com/upper/db/v4"
+)
+
+// AllPermissions, granted to a role, gives it every permission.
+const AllPermissions = "*"
+
+// permissionsCacheTTL is only a backstop: every change to roles or
+// permissions clears the cached lists it affects.
+const permissionsCacheTTL = time.Hour
+
+// permissionsCacheTag tags every cached permission list.
+const permissionsCacheTag = "permissions"
+
+type Permission struct {
+	ID        int       `db:"id,omitempty"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (p *Permission) Table() string {
+	return "permissions"
+}
+
+func (p *Permission) GetAll() ([]*Permission, error) {
+	var all []*Permission
+	res := reader().Collection(p.Table()).Find().OrderBy("name")
+	if err := res.All(&all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (p *Permission) GetByName(name string) (*Permission, error) {
+	var one Permission
+	res := upper.Collection(p.Table()).Find(udb.Cond{"name": name})
+	if err := res.One(&one); err != nil {
+		return nil, err
+	}
+	return &one, nil
+}
+
+// FirstOrCreate returns the permission called name, creating it if needed.
+func (p *Permission) FirstOrCreate(name string) (*Permission, error) {
+	one, err := p.GetByName(name)
+	if err == nil || !errors.Is(err, udb.ErrNoMoreRows) {
+		return one, err
+	}
+
+	now := time.Now()
+	one = &Permission{Name: name, CreatedAt: now, UpdatedAt: now}
+	res, err := upper.Collection(p.Table()).Insert(one)
+	if err != nil {
+		return nil, err
+	}
+	one.ID = getInsertedID(res.ID())
+	return one, nil
+}
+
+// ForUser returns the names of the permissions userID has through its roles.
+func (p *Permission) ForUser(userID int) ([]string, error) {
+	if appCache == nil {
+		return p.queryForUser(userID)
+	}
+
+	var names []string
+	err := appCache.Tags(permissionsCacheTag).Remember(permissionsCacheKey(userID), permissionsCacheTTL, &names, func() (interface{}, error) {
+		return p.queryForUser(userID)
+	})
+	return names, err
+}
+
+// queryForUser reads from the primary: what it returns is cached, so a
+// replica that has not caught up with a grant or revoke would be remembered.
+func (p *Permission) queryForUser(userID int) ([]string, error) {
+	rows, err := upper.SQL().Query(`
+		SELECT DISTINCT p.name
+		FROM permissions p
+		JOIN role_permissions rp ON rp.permission_id = p.id
+		JOIN user_roles ur ON ur.role_id = rp.role_id
+		WHERE ur.user_id = ?
+		ORDER BY p.name`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// UserHas reports whether userID has the permission called name, directly or
+// through AllPermissions.
+func (p *Permission) UserHas(userID int, name string) (bool, error) {
+	names, err := p.ForUser(userID)
+	if err != nil {
+		return false, err
+	}
+	for _, n := range names {
+		if n == name || n == AllPermissions {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func permissionsCacheKey(userID int) string {
+	return fmt.Sprintf("user-permissions:%d", userID)
+}
+
+// forgetPermissions drops the cached permissions of userID, or of every user
+// when userID is 0.
+func forgetPermissions(userID int) error {
+	if appCache == nil {
+		return nil
+	}
+	if userID == 0 {
+		return appCache.Tags(permissionsCacheTag).Flush()
+	}
+	return appCache.Forget(permissionsCacheKey(userID))
+}
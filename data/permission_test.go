@@ -0,0 +1,61 @@
+package data
+
+import (
+	"testing"
+
+	"myapp/cache"
+)
+
+func TestPermissionsFollowGrantsThroughTheCache(t *testing.T) {
+	memory := cache.NewMemory(cache.MemoryOptions{})
+	defer func() {
+		_ = memory.Close()
+	}()
+	store, err := cache.NewStore(memory)
+	if err != nil {
+		t.Fatal(err)
+	}
+	UseCache(store)
+	defer UseCache(nil)
+
+	var u User
+	userID, err := u.Insert(User{FirstName: "Edsger", LastName: "Dijkstra", Email: "edsger@example.com", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = u.Delete(userID)
+	}()
+
+	var r Role
+	var p Permission
+	has := func() bool {
+		t.Helper()
+		ok, err := p.UserHas(userID, "posts.edit")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if err := r.Assign(userID, "editor"); err != nil {
+		t.Fatal(err)
+	}
+	if has() {
+		t.Error("expected no permission before the grant")
+	}
+
+	if err := r.Grant("editor", "posts.edit"); err != nil {
+		t.Fatal(err)
+	}
+	if !has() {
+		t.Error("expected the grant to clear the cached permissions")
+	}
+
+	if err := r.Revoke("editor", "posts.edit"); err != nil {
+		t.Fatal(err)
+	}
+	if has() {
+		t.Error("expected the revoke to clear the cached permissions")
+	}
+}
@@ -0,0 +1,97 @@
+package data
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+
+	udb "github.com/upper/db/v4"
+)
+
+// RecoveryCodeCount is how many recovery codes a user gets at a time.
+const RecoveryCodeCount = 10
+
+// RecoveryCode is a one-time code that stands in for a TOTP code when the
+// authenticator is lost. Codes carry 80 random bits, so a plain hash is
+// enough to store them safely.
+type RecoveryCode struct {
+	ID        int       `db:"id,omitempty"`
+	UserID    int       `db:"user_id"`
+	CodeHash  string    `db:"code_hash"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (c *RecoveryCode) Table() string {
+	return "recovery_codes"
+}
+
+// Generate replaces the recovery codes of userID with RecoveryCodeCount new
+// ones and returns them. They cannot be shown again later.
+func (c *RecoveryCode) Generate(userID int) ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		s := base32.StdEncoding.EncodeToString(b)
+		codes[i] = strings.Join([]string{s[0:4], s[4:8], s[8:12], s[12:16]}, "-")
+	}
+
+	err := upper.Tx(func(sess udb.Session) error {
+		if err := sess.Collection(c.Table()).Find(udb.Cond{"user_id": userID}).Delete(); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, code := range codes {
+			_, err := sess.Collection(c.Table()).Insert(RecoveryCode{
+				UserID:    userID,
+				CodeHash:  hashToken(normalizeRecoveryCode(code)),
+				CreatedAt: now,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Use spends code for userID, reporting whether it was one of the user's
+// unused codes.
+func (c *RecoveryCode) Use(userID int, code string) (bool, error) {
+	res, err := upper.SQL().Exec(
+		"DELETE FROM recovery_codes WHERE user_id = ? AND code_hash = ?",
+		userID, hashToken(normalizeRecoveryCode(code)),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Remaining returns how many unused recovery codes userID has.
+func (c *RecoveryCode) Remaining(userID int) (int, error) {
+	n, err := upper.Collection(c.Table()).Find(udb.Cond{"user_id": userID}).Count()
+	return int(n), err
+}
+
+// normalizeRecoveryCode accepts codes typed in lower case or without dashes.
+func normalizeRecoveryCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
@@ -0,0 +1,106 @@
+package data
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	udb "github.com/upper/db/v4"
+)
+
+// RememberTokenLifetime matches the lifetime of the remember-me cookie.
+const RememberTokenLifetime = 365 * 24 * time.Hour
+
+var ErrInvalidRememberToken = errors.New("invalid or expired remember token")
+
+// RememberToken is a remember-me login. Only a hash of the token is stored,
+// so a leaked table cannot be used to log in.
+type RememberToken struct {
+	ID            int       `db:"id,omitempty"`
+	UserID        int       `db:"user_id"`
+	RememberToken string    `db:"remember_token"`
+	ExpiresAt     time.Time `db:"expires_at"`
+	CreatedAt     time.Time `db:"created_at"`
+	UpdatedAt     time.Time `db:"updated_at"`
+}
+
+func (t *RememberToken) Table() string {
+	return "remember_tokens"
+}
+
+func (t *RememberToken) InsertToken(userID int, token string) error {
+	return t.insert(upper, userID, token)
+}
+
+// Validate returns the stored token when token belongs to userID and has not
+// expired, and ErrInvalidRememberToken otherwise.
+func (t *RememberToken) Validate(userID int, token string) (*RememberToken, error) {
+	var one RememberToken
+	res := upper.Collection(t.Table()).Find(udb.Cond{"remember_token": hashToken(token)})
+	if err := res.One(&one); err != nil {
+		if errors.Is(err, udb.ErrNoMoreRows) {
+			return nil, ErrInvalidRememberToken
+		}
+		return nil, err
+	}
+
+	if one.UserID != userID || time.Now().After(one.ExpiresAt) {
+		return nil, ErrInvalidRememberToken
+	}
+	return &one, nil
+}
+
+// Rotate replaces oldToken with newToken in a single transaction, so a stolen
+// cookie stops working as soon as the real user comes back. Only one of
+// several requests rotating the same token succeeds; the others, and an
+// oldToken that is not userID's, get ErrInvalidRememberToken.
+func (t *RememberToken) Rotate(userID int, oldToken, newToken string) error {
+	return upper.Tx(func(sess udb.Session) error {
+		res, err := sess.SQL().Exec(
+			"DELETE FROM remember_tokens WHERE remember_token = ? AND user_id = ?",
+			hashToken(oldToken), userID,
+		)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n != 1 {
+			return ErrInvalidRememberToken
+		}
+		return t.insert(sess, userID, newToken)
+	})
+}
+
+func (t *RememberToken) Delete(token string) error {
+	res := upper.Collection(t.Table()).Find(udb.Cond{"remember_token": hashToken(token)})
+	return res.Delete()
+}
+
+// DeleteForUser logs userID out of every device that was remembered.
+func (t *RememberToken) DeleteForUser(userID int) error {
+	res := upper.Collection(t.Table()).Find(udb.Cond{"user_id": userID})
+	return res.Delete()
+}
+
+func (t *RememberToken) insert(sess udb.Session, userID int, token string) error {
+	now := time.Now()
+	_, err := sess.Collection(t.Table()).Insert(RememberToken{
+		UserID:        userID,
+		RememberToken: hashToken(token),
+		ExpiresAt:     now.Add(RememberTokenLifetime),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	})
+	return err
+}
+
+// hashToken returns the hex SHA-256 of a random token. Tokens are long and
+// random, so a fast hash is enough and lets them be looked up directly.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
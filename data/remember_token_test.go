@@ -0,0 +1,45 @@
+package data
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRememberTokenRotatesOnce(t *testing.T) {
+	var u User
+	userID, err := u.Insert(User{FirstName: "Rotate", LastName: "User", Email: "rotate@example.com", Password: "password", Active: 1})
+	if err != nil {
+		t.Fatal("insert:", err)
+	}
+	defer func() {
+		_ = u.Delete(userID)
+	}()
+
+	var tokens RememberToken
+	if err := tokens.InsertToken(userID, "old"); err != nil {
+		t.Fatal("insert token:", err)
+	}
+	defer func() {
+		_ = tokens.DeleteForUser(userID)
+	}()
+
+	if err := tokens.Rotate(userID+1, "old", "stolen"); !errors.Is(err, ErrInvalidRememberToken) {
+		t.Errorf("expected another user's token to be refused, got %v", err)
+	}
+	if err := tokens.Rotate(userID, "old", "new-1"); err != nil {
+		t.Fatal("rotate:", err)
+	}
+	if err := tokens.Rotate(userID, "old", "new-2"); !errors.Is(err, ErrInvalidRememberToken) {
+		t.Errorf("expected a second rotation of the same token to fail, got %v", err)
+	}
+
+	for token, valid := range map[string]bool{"old": false, "stolen": false, "new-1": true, "new-2": false} {
+		_, err := tokens.Validate(userID, token)
+		if valid && err != nil {
+			t.Errorf("expected %s to be valid, got %v", token, err)
+		}
+		if !valid && !errors.Is(err, ErrInvalidRememberToken) {
+			t.Errorf("expected %s to be invalid, got %v", token, err)
+		}
+	}
+}
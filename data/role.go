@@ -0,0 +1,163 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	udb "github.com/upper/db/v4"
+)
+
+type Role struct {
+	ID        int       `db:"id,omitempty"`
+	Name      string    `db:"name"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (r *Role) Table() string {
+	return "roles"
+}
+
+func (r *Role) GetAll() ([]*Role, error) {
+	var all []*Role
+	res := reader().Collection(r.Table()).Find().OrderBy("name")
+	if err := res.All(&all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (r *Role) GetByName(name string) (*Role, error) {
+	var one Role
+	res := upper.Collection(r.Table()).Find(udb.Cond{"name": name})
+	if err := res.One(&one); err != nil {
+		return nil, err
+	}
+	return &one, nil
+}
+
+// FirstOrCreate returns the role called name, creating it if needed.
+func (r *Role) FirstOrCreate(name string) (*Role, error) {
+	one, err := r.GetByName(name)
+	if err == nil || !errors.Is(err, udb.ErrNoMoreRows) {
+		return one, err
+	}
+
+	now := time.Now()
+	one = &Role{Name: name, CreatedAt: now, UpdatedAt: now}
+	res, err := upper.Collection(r.Table()).Insert(one)
+	if err != nil {
+		return nil, err
+	}
+	one.ID = getInsertedID(res.ID())
+	return one, nil
+}
+
+// ForUser returns the roles assigned to userID.
+func (r *Role) ForUser(userID int) ([]*Role, error) {
+	var all []*Role
+	err := reader().SQL().
+		Select("r.*").
+		From("roles AS r").
+		Join("user_roles AS ur").On("ur.role_id = r.id").
+		Where("ur.user_id", userID).
+		OrderBy("r.name").
+		All(&all)
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// Delete removes the role and takes it away from every user.
+func (r *Role) Delete(id int) error {
+	if err := upper.Collection(r.Table()).Find(udb.Cond{"id": id}).Delete(); err != nil {
+		return err
+	}
+	return forgetPermissions(0)
+}
+
+// Assign gives userID the role called name, creating the role if needed.
+func (r *Role) Assign(userID int, name string) error {
+	role, err := r.FirstOrCreate(name)
+	if err != nil {
+		return err
+	}
+
+	link := upper.Collection("user_roles")
+	exists, err := link.Find(udb.Cond{"user_id": userID, "role_id": role.ID}).Exists()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := link.Insert(map[string]interface{}{"user_id": userID, "role_id": role.ID}); err != nil {
+			return err
+		}
+	}
+	return forgetPermissions(userID)
+}
+
+// Unassign takes the role called name away from userID.
+func (r *Role) Unassign(userID int, name string) error {
+	role, err := r.GetByName(name)
+	if errors.Is(err, udb.ErrNoMoreRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	res := upper.Collection("user_roles").Find(udb.Cond{"user_id": userID, "role_id": role.ID})
+	if err := res.Delete(); err != nil {
+		return err
+	}
+	return forgetPermissions(userID)
+}
+
+// Grant gives the role called name a permission, creating either if needed.
+func (r *Role) Grant(name, permission string) error {
+	role, err := r.FirstOrCreate(name)
+	if err != nil {
+		return err
+	}
+	perm, err := (&Permission{}).FirstOrCreate(permission)
+	if err != nil {
+		return err
+	}
+
+	link := upper.Collection("role_permissions")
+	exists, err := link.Find(udb.Cond{"role_id": role.ID, "permission_id": perm.ID}).Exists()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if _, err := link.Insert(map[string]interface{}{"role_id": role.ID, "permission_id": perm.ID}); err != nil {
+			return err
+		}
+	}
+	return forgetPermissions(0)
+}
+
+// Revoke takes a permission away from the role called name.
+func (r *Role) Revoke(name, permission string) error {
+	role, err := r.GetByName(name)
+	if errors.Is(err, udb.ErrNoMoreRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	perm, err := (&Permission{}).GetByName(permission)
+	if errors.Is(err, udb.ErrNoMoreRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	res := upper.Collection("role_permissions").Find(udb.Cond{"role_id": role.ID, "permission_id": perm.ID})
+	if err := res.Delete(); err != nil {
+		return err
+	}
+	return forgetPermissions(0)
+}
@@ -0,0 +1,28 @@
+package data
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+
+	"myapp/database/dbtest"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "data-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	d, err := dbtest.Open("..", dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	New(d)
+
+	code := m.Run()
+	_ = d.Primary.Close()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
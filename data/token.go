@@ -0,0 +1,160 @@
+package data
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	udb "github.com/upper/db/v4"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Token is an API token. The plain text is only known when the token is
+// issued; the table holds its hash.
+type Token struct {
+	ID        int       `db:"id,omitempty"`
+	UserID    int       `db:"user_id"`
+	Name      string    `db:"name"`
+	PlainText string    `db:"-"`
+	Hash      string    `db:"token_hash"`
+	Scopes    string    `db:"scopes"`
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (t *Token) Table() string {
+	return "tokens"
+}
+
+// Issue creates a token for userID that expires after ttl. Its PlainText is
+// set on the returned value and must be handed to the client now, since it
+// cannot be recovered later.
+func (t *Token) Issue(userID int, name string, ttl time.Duration, scopes []string) (*Token, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	plainText := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+
+	now := time.Now()
+	token := Token{
+		UserID:    userID,
+		Name:      name,
+		Hash:      hashToken(plainText),
+		Scopes:    strings.Join(strings.Fields(strings.Join(scopes, " ")), " "),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	res, err := upper.Collection(t.Table()).Insert(token)
+	if err != nil {
+		return nil, err
+	}
+	token.ID = getInsertedID(res.ID())
+	token.PlainText = plainText
+	return &token, nil
+}
+
+func (t *Token) Get(id int) (*Token, error) {
+	var one Token
+	res := upper.Collection(t.Table()).Find(udb.Cond{"id": id})
+	if err := res.One(&one); err != nil {
+		return nil, err
+	}
+	return &one, nil
+}
+
+func (t *Token) GetForUser(userID int) ([]*Token, error) {
+	var all []*Token
+	res := reader().Collection(t.Table()).Find(udb.Cond{"user_id": userID}).OrderBy("id")
+	if err := res.All(&all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// GetUserForToken returns the active user plainText belongs to, along with
+// the token, or ErrInvalidToken.
+func (t *Token) GetUserForToken(plainText string) (*User, *Token, error) {
+	var token Token
+	res := upper.Collection(t.Table()).Find(udb.Cond{"token_hash": hashToken(plainText)})
+	if err := res.One(&token); err != nil {
+		if errors.Is(err, udb.ErrNoMoreRows) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+	if time.Now().After(token.ExpiresAt) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	var user User
+	res = upper.Collection(user.Table()).Find(udb.Cond{"id": token.UserID})
+	if err := res.One(&user); err != nil {
+		if errors.Is(err, udb.ErrNoMoreRows) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, err
+	}
+	if user.Active == 0 {
+		return nil, nil, ErrInvalidToken
+	}
+
+	return &user, &token, nil
+}
+
+func (t *Token) Delete(id int) error {
+	res := upper.Collection(t.Table()).Find(udb.Cond{"id": id})
+	return res.Delete()
+}
+
+// DeleteForUser deletes every token of userID and returns them, so their
+// cache entries can be removed too.
+func (t *Token) DeleteForUser(userID int) ([]*Token, error) {
+	var deleted []*Token
+	err := upper.Tx(func(sess udb.Session) error {
+		res := sess.Collection(t.Table()).Find(udb.Cond{"user_id": userID})
+		if err := res.All(&deleted); err != nil {
+			return err
+		}
+		return res.Delete()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deleted, nil
+}
+
+// ScopeList returns the scopes the token was issued with.
+func (t *Token) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+func (t *Token) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// CacheKey is the key a token lookup is cached under. It is built from the
+// hash, so the cache never holds a usable token.
+func (t *Token) CacheKey() string {
+	return tokenCacheKey(t.Hash)
+}
+
+// TokenCacheKey returns the cache key for the token with plainText.
+func TokenCacheKey(plainText string) string {
+	return tokenCacheKey(hashToken(plainText))
+}
+
+func tokenCacheKey(hash string) string {
+	return "api-token:" + hash
+}
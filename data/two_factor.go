@@ -0,0 +1,145 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	udb "github.com/upper/db/v4"
+)
+
+// TwoFactor is a user's TOTP enrolment. Secret is encrypted by the caller with
+// the application key before it is stored. LastStep is the time step of the
+// last code accepted, so a code cannot be used twice. FailedCodes counts the
+// wrong codes in a row, and LockedUntil is the Unix time until which no code
+// is checked after too many of them.
+type TwoFactor struct {
+	ID          int       `db:"id,omitempty"`
+	UserID      int       `db:"user_id"`
+	Secret      string    `db:"secret"`
+	Enabled     int       `db:"enabled"`
+	LastStep    int64     `db:"last_step"`
+	FailedCodes int       `db:"failed_codes"`
+	LockedUntil int64     `db:"locked_until"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+func (t *TwoFactor) Table() string {
+	return "two_factors"
+}
+
+// GetForUser returns the enrolment of userID, or nil when there is none.
+func (t *TwoFactor) GetForUser(userID int) (*TwoFactor, error) {
+	var one TwoFactor
+	res := upper.Collection(t.Table()).Find(udb.Cond{"user_id": userID})
+	if err := res.One(&one); err != nil {
+		if errors.Is(err, udb.ErrNoMoreRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &one, nil
+}
+
+// EnabledFor reports whether userID has finished enrolling.
+func (t *TwoFactor) EnabledFor(userID int) (bool, error) {
+	one, err := t.GetForUser(userID)
+	if err != nil {
+		return false, err
+	}
+	return one != nil && one.Enabled != 0, nil
+}
+
+// Begin starts enrolling userID with an encrypted secret, replacing an
+// enrolment that was never confirmed. It fails when 2FA is already enabled.
+func (t *TwoFactor) Begin(userID int, encryptedSecret string) error {
+	return upper.Tx(func(sess udb.Session) error {
+		res := sess.Collection(t.Table()).Find(udb.Cond{"user_id": userID, "enabled": 0})
+		if err := res.Delete(); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		_, err := sess.Collection(t.Table()).Insert(TwoFactor{
+			UserID:    userID,
+			Secret:    encryptedSecret,
+			CreatedAt: now,
+			UpdatedAt: now,
+		})
+		return err
+	})
+}
+
+// Enable confirms the enrolment of userID, recording step as used.
+func (t *TwoFactor) Enable(userID int, step int64) error {
+	res := upper.Collection(t.Table()).Find(udb.Cond{"user_id": userID})
+	return res.Update(udb.Cond{"enabled": 1, "last_step": step, "updated_at": time.Now()})
+}
+
+// Disable removes the enrolment and the recovery codes of userID.
+func (t *TwoFactor) Disable(userID int) error {
+	return upper.Tx(func(sess udb.Session) error {
+		if err := sess.Collection(t.Table()).Find(udb.Cond{"user_id": userID}).Delete(); err != nil {
+			return err
+		}
+		return sess.Collection("recovery_codes").Find(udb.Cond{"user_id": userID}).Delete()
+	})
+}
+
+// UseStep records that the code for step was used. It returns false when that
+// step, or a later one, was used already; of two requests racing with the
+// same code only one gets true.
+func (t *TwoFactor) UseStep(userID int, step int64) (bool, error) {
+	res, err := upper.SQL().Exec(
+		"UPDATE two_factors SET last_step = ?, updated_at = ? WHERE user_id = ? AND last_step < ?",
+		step, time.Now(), userID, step,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// LockedAt reports whether codes are refused at now because too many wrong
+// ones were entered.
+func (t *TwoFactor) LockedAt(now time.Time) bool {
+	return now.Unix() < t.LockedUntil
+}
+
+// Fail records a wrong code for userID. The max-th wrong code in a row locks
+// the enrolment until now plus lockout and starts the count again; Fail then
+// returns true.
+func (t *TwoFactor) Fail(userID, max int, lockout time.Duration, now time.Time) (bool, error) {
+	var locked bool
+	err := upper.Tx(func(sess udb.Session) error {
+		_, err := sess.SQL().Exec(
+			"UPDATE two_factors SET failed_codes = failed_codes + 1, updated_at = ? WHERE user_id = ?",
+			now, userID,
+		)
+		if err != nil {
+			return err
+		}
+		res, err := sess.SQL().Exec(
+			"UPDATE two_factors SET failed_codes = 0, locked_until = ? WHERE user_id = ? AND failed_codes >= ?",
+			now.Add(lockout).Unix(), userID, max,
+		)
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		locked = n == 1
+		return err
+	})
+	return locked, err
+}
+
+// ResetFailures forgets the wrong codes entered by userID, once a right one
+// was.
+func (t *TwoFactor) ResetFailures(userID int) error {
+	res := upper.Collection(t.Table()).Find(udb.Cond{"user_id": userID})
+	return res.Update(udb.Cond{"failed_codes": 0})
+}
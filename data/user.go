@@ -0,0 +1,122 @@
+package data
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	udb "github.com/upper/db/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const passwordCost = 12
+
+type User struct {
+	ID        int       `db:"id,omitempty" json:"id"`
+	FirstName string    `db:"first_name" json:"first_name"`
+	LastName  string    `db:"last_name" json:"last_name"`
+	Email     string    `db:"email" json:"email"`
+	Active    int       `db:"user_active" json:"active"`
+	Password  string    `db:"password" json:"-"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+func (u *User) Table() string {
+	return "users"
+}
+
+func (u *User) GetAll() ([]*User, error) {
+	var all []*User
+	res := reader().Collection(u.Table()).Find().OrderBy("last_name")
+	if err := res.All(&all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (u *User) GetByEmail(email string) (*User, error) {
+	var one User
+	res := reader().Collection(u.Table()).Find(udb.Cond{"email": normalizeEmail(email)})
+	if err := res.One(&one); err != nil {
+		return nil, err
+	}
+	return &one, nil
+}
+
+func (u *User) Get(id int) (*User, error) {
+	return u.get(reader(), id)
+}
+
+// GetFromPrimary is Get reading from the primary, for a user that may have
+// been written a moment ago.
+func (u *User) GetFromPrimary(id int) (*User, error) {
+	return u.get(upper, id)
+}
+
+func (u *User) get(sess udb.Session, id int) (*User, error) {
+	var one User
+	res := sess.Collection(u.Table()).Find(udb.Cond{"id": id})
+	if err := res.One(&one); err != nil {
+		return nil, err
+	}
+	return &one, nil
+}
+
+// Insert hashes the plain text password in user before storing it.
+func (u *User) Insert(user User) (int, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(user.Password), passwordCost)
+	if err != nil {
+		return 0, err
+	}
+
+	user.Email = normalizeEmail(user.Email)
+	user.Password = string(hash)
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = time.Now()
+
+	res, err := upper.Collection(u.Table()).Insert(user)
+	if err != nil {
+		return 0, err
+	}
+	return getInsertedID(res.ID()), nil
+}
+
+// Update stores user as it is; use ResetPassword to change the password.
+func (u *User) Update(user User) error {
+	user.Email = normalizeEmail(user.Email)
+	user.UpdatedAt = time.Now()
+	res := upper.Collection(u.Table()).Find(udb.Cond{"id": user.ID})
+	return res.Update(&user)
+}
+
+func (u *User) Delete(id int) error {
+	res := upper.Collection(u.Table()).Find(udb.Cond{"id": id})
+	return res.Delete()
+}
+
+func (u *User) ResetPassword(id int, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordCost)
+	if err != nil {
+		return err
+	}
+
+	res := upper.Collection(u.Table()).Find(udb.Cond{"id": id})
+	return res.Update(udb.Cond{"password": string(hash), "updated_at": time.Now()})
+}
+
+// PasswordMatches reports whether plainText is the user's password.
+func (u *User) PasswordMatches(plainText string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(plainText))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,65 @@
+package data
+
+import (
+	"errors"
+	"time"
+
+	udb "github.com/upper/db/v4"
+)
+
+// UserIdentity links a user to their account at an external identity
+// provider, so they can log in with it.
+type UserIdentity struct {
+	ID        int       `db:"id,omitempty"`
+	UserID    int       `db:"user_id"`
+	Provider  string    `db:"provider"`
+	Subject   string    `db:"subject"`
+	Email     string    `db:"email"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func (i *UserIdentity) Table() string {
+	return "user_identities"
+}
+
+// GetByProvider returns the link for subject at provider, or nil when the
+// account is not linked to a user. It reads from the primary, so a link made
+// by a login a moment ago is found.
+func (i *UserIdentity) GetByProvider(provider, subject string) (*UserIdentity, error) {
+	var one UserIdentity
+	res := upper.Collection(i.Table()).Find(udb.Cond{"provider": provider, "subject": subject})
+	if err := res.One(&one); err != nil {
+		if errors.Is(err, udb.ErrNoMoreRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &one, nil
+}
+
+func (i *UserIdentity) GetForUser(userID int) ([]*UserIdentity, error) {
+	var all []*UserIdentity
+	res := reader().Collection(i.Table()).Find(udb.Cond{"user_id": userID}).OrderBy("provider")
+	if err := res.All(&all); err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+func (i *UserIdentity) Insert(identity UserIdentity) (int, error) {
+	identity.Email = normalizeEmail(identity.Email)
+	identity.CreatedAt = time.Now()
+	identity.UpdatedAt = time.Now()
+
+	res, err := upper.Collection(i.Table()).Insert(identity)
+	if err != nil {
+		return 0, err
+	}
+	return getInsertedID(res.ID()), nil
+}
+
+func (i *UserIdentity) Delete(id int) error {
+	res := upper.Collection(i.Table()).Find(udb.Cond{"id": id})
+	return res.Delete()
+}
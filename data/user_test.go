@@ -0,0 +1,49 @@
+package data
+
+import "testing"
+
+func TestUser(t *testing.T) {
+	var u User
+	id, err := u.Insert(User{
+		FirstName: "Ada",
+		LastName:  "Lovelace",
+		Email:     " Ada@Example.com ",
+		Password:  "correct horse",
+		Active:    1,
+	})
+	if err != nil {
+		t.Fatal("insert:", err)
+	}
+	defer func() {
+		_ = u.Delete(id)
+	}()
+
+	one, err := u.GetByEmail("ada@example.com")
+	if err != nil {
+		t.Fatal("get by email:", err)
+	}
+	if one.ID != id {
+		t.Errorf("expected id %d, got %d", id, one.ID)
+	}
+	if one.Email != "ada@example.com" {
+		t.Errorf("expected the email to be normalized, got %q", one.Email)
+	}
+
+	if ok, err := one.PasswordMatches("correct horse"); err != nil || !ok {
+		t.Errorf("expected the password to match, got %v, %v", ok, err)
+	}
+	if ok, _ := one.PasswordMatches("wrong"); ok {
+		t.Error("expected a wrong password not to match")
+	}
+
+	if err := u.ResetPassword(id, "battery staple"); err != nil {
+		t.Fatal("reset password:", err)
+	}
+	one, err = u.GetFromPrimary(id)
+	if err != nil {
+		t.Fatal("get from primary:", err)
+	}
+	if ok, _ := one.PasswordMatches("battery staple"); !ok {
+		t.Error("expected the new password to match")
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"myapp/config"
+	"myapp/database"
+
+	"github.com/alexedwards/scs/mysqlstore"
+	"github.com/alexedwards/scs/postgresstore"
+	"github.com/alexedwards/scs/sqlite3store"
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/lozhkindm/celeritas"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// The framework's createDB only knows how to build a Postgres DSN and kills
+// the process when the first ping fails, so the skeleton opens the database
+// itself. While celeritas.New runs, DATABASE_TYPE (and a database-backed
+// SESSION_TYPE) are blanked so the framework leaves the pool to us.
+var frameworkDBEnv = []string{"DATABASE_TYPE", "SESSION_TYPE"}
+
+func isDatabaseSession(sessionType string) bool {
+	switch strings.ToLower(sessionType) {
+	case "mysql", "mariadb", "postgres", "postgresql", "sqlite", "sqlite3":
+		return true
+	}
+	return false
+}
+
+// hideDBEnv blanks the database settings the framework would act on and
+// returns a function that restores them.
+func hideDBEnv() func() {
+	saved := make(map[string]string)
+	for _, key := range frameworkDBEnv {
+		val := os.Getenv(key)
+		if key == "SESSION_TYPE" && !isDatabaseSession(val) {
+			continue
+		}
+		saved[key] = val
+		_ = os.Setenv(key, "")
+	}
+
+	return func() {
+		for key, val := range saved {
+			_ = os.Setenv(key, val)
+		}
+	}
+}
+
+// connectDB opens the pool for the configured database and attaches it, and
+// the database session store when one is configured, to cel. Replicas listed
+// in DATABASE_REPLICA_DSNS are opened alongside it.
+func connectDB(cel *celeritas.Celeritas, cfg *config.Config) (*database.Database, error) {
+	dbc := cfg.Database
+	if dbc.Type == "" {
+		return nil, nil
+	}
+
+	dsn, err := buildDSN(cel, dbc)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := sql.Open(driverName(dbc.Type), dsn)
+	if err != nil {
+		return nil, err
+	}
+	configurePool(pool, dbc)
+	if isSQLite(dbc.Type) && sqliteFile(cel, dbc) == sqliteMemory {
+		// every connection to :memory: sees its own database, so keep exactly one alive
+		pool.SetMaxOpenConns(1)
+		pool.SetMaxIdleConns(1)
+		pool.SetConnMaxLifetime(0)
+	}
+	if err := pingWithRetry(cel, pool, dbc.ConnectRetries); err != nil {
+		_ = pool.Close()
+		return nil, err
+	}
+	cel.DB.DataType = dbc.Type
+	cel.DB.Pool = pool
+
+	switch strings.ToLower(cfg.Session.Type) {
+	case "mysql", "mariadb":
+		cel.Session.Store = mysqlstore.New(pool)
+	case "postgres", "postgresql":
+		cel.Session.Store = postgresstore.New(pool)
+	case "sqlite", "sqlite3":
+		cel.Session.Store = sqlite3store.New(pool)
+	}
+
+	replicas, err := openReplicas(cel, dbc)
+	if err != nil {
+		_ = pool.Close()
+		return nil, err
+	}
+
+	return database.New(dbc.Type, pool, replicas...), nil
+}
+
+// openReplicas opens one pool per replica DSN. A replica that is down at
+// startup is not fatal; its health check keeps it out of rotation until it
+// answers.
+func openReplicas(cel *celeritas.Celeritas, dbc config.DatabaseConfig) ([]*database.Replica, error) {
+	var replicas []*database.Replica
+
+	for _, dsn := range dbc.ReplicaDSNs {
+		pool, err := sql.Open(driverName(dbc.Type), dsn)
+		if err != nil {
+			for _, r := range replicas {
+				_ = r.Pool.Close()
+			}
+			return nil, err
+		}
+		configurePool(pool, dbc)
+
+		health := database.NewHealth(pool, dbc.HealthCheckInterval)
+		health.OnError = func(err error) {
+			cel.ErrorLog.Println("replica health check failed:", err)
+		}
+		health.Start()
+
+		replicas = append(replicas, &database.Replica{Pool: pool, Health: health})
+	}
+
+	return replicas, nil
+}
+
+func configurePool(pool *sql.DB, dbc config.DatabaseConfig) {
+	pool.SetMaxOpenConns(dbc.MaxOpenConns)
+	pool.SetMaxIdleConns(dbc.MaxIdleConns)
+	pool.SetConnMaxLifetime(dbc.ConnMaxLifetime)
+}
+
+// sleep is time.Sleep, replaced in tests.
+var sleep = time.Sleep
+
+// pingWithRetry waits for the database to accept connections, backing off
+// exponentially between retries.
+func pingWithRetry(cel *celeritas.Celeritas, pool *sql.DB, retries int) error {
+	const maxBackoff = 30 * time.Second
+
+	backoff := time.Second
+	for attempt := 0; ; attempt++ {
+		err := pool.Ping()
+		if err == nil {
+			return nil
+		}
+		if attempt >= retries {
+			return fmt.Errorf("database unreachable after %d attempts: %w", attempt+1, err)
+		}
+
+		cel.ErrorLog.Printf("database not ready (%s), retrying in %s", err, backoff)
+		sleep(backoff)
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// buildDSN returns the database/sql DSN for the configured database.
+func buildDSN(cel *celeritas.Celeritas, dbc config.DatabaseConfig) (string, error) {
+	switch dbc.Type {
+	case "postgres", "postgresql":
+		dsn := fmt.Sprintf(
+			"host=%s port=%d user=%s dbname=%s sslmode=%s timezone=UTC connect_timeout=5",
+			dbc.Host,
+			dbc.Port,
+			dbc.User,
+			dbc.Name,
+			dbc.SSLMode,
+		)
+		if dbc.Password != "" {
+			dsn = fmt.Sprintf("%s password=%s", dsn, dbc.Password)
+		}
+		return dsn, nil
+	case "mysql", "mariadb":
+		return mysqlConfig(dbc).FormatDSN(), nil
+	case "sqlite", "sqlite3":
+		return fmt.Sprintf("file:%s?_foreign_keys=1&_busy_timeout=5000", sqliteFile(cel, dbc)), nil
+	default:
+		return "", fmt.Errorf("unsupported database type %q", dbc.Type)
+	}
+}
+
+// driverName returns the database/sql driver registered for dbType.
+func driverName(dbType string) string {
+	switch dbType {
+	case "postgres", "postgresql":
+		return "pgx"
+	case "sqlite", "sqlite3":
+		return "sqlite3"
+	default:
+		return dbType
+	}
+}
+
+func isSQLite(dbType string) bool {
+	return dbType == "sqlite" || dbType == "sqlite3"
+}
+
+const sqliteMemory = ":memory:"
+
+// sqliteFile returns the database file for DATABASE_NAME: the path itself
+// when it is absolute, the file inside the data folder otherwise, or
+// :memory: for an in-process database.
+func sqliteFile(cel *celeritas.Celeritas, dbc config.DatabaseConfig) string {
+	name := dbc.Name
+	if name == sqliteMemory {
+		return sqliteMemory
+	}
+	if name == "" {
+		name = "celeritas"
+	}
+	if filepath.Ext(name) == "" {
+		name = fmt.Sprintf("%s.db", name)
+	}
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(cel.RootPath, "data", name)
+}
+
+// migrationURL returns the URL golang-migrate expects for the configured
+// database.
+func migrationURL(cel *celeritas.Celeritas, dbc config.DatabaseConfig) (string, error) {
+	switch dbc.Type {
+	case "postgres", "postgresql":
+		u := url.URL{
+			Scheme: "postgres",
+			Host:   net.JoinHostPort(dbc.Host, strconv.Itoa(dbc.Port)),
+			Path:   dbc.Name,
+		}
+		if dbc.Password != "" {
+			u.User = url.UserPassword(dbc.User, dbc.Password)
+		} else {
+			u.User = url.User(dbc.User)
+		}
+		u.RawQuery = url.Values{"sslmode": {dbc.SSLMode}}.Encode()
+		return u.String(), nil
+	case "mysql", "mariadb":
+		cfg := mysqlConfig(dbc)
+		cfg.MultiStatements = true
+		return fmt.Sprintf("mysql://%s", cfg.FormatDSN()), nil
+	case "sqlite", "sqlite3":
+		file := sqliteFile(cel, dbc)
+		if file == sqliteMemory {
+			return "", errors.New("an in-memory sqlite database cannot be migrated by url")
+		}
+		return fmt.Sprintf("sqlite3://%s?_foreign_keys=1", file), nil
+	default:
+		return "", fmt.Errorf("unsupported database type %q", dbc.Type)
+	}
+}
+
+func mysqlConfig(dbc config.DatabaseConfig) *mysql.Config {
+	cfg := mysql.NewConfig()
+	cfg.User = dbc.User
+	cfg.Passwd = dbc.Password
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(dbc.Host, strconv.Itoa(dbc.Port))
+	cfg.DBName = dbc.Name
+	cfg.TLSConfig = mysqlTLSMode(dbc.SSLMode)
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	cfg.Timeout = 5 * time.Second
+	cfg.Collation = dbc.Collation
+	return cfg
+}
+
+// mysqlTLSMode maps the Postgres style DATABASE_SSL_MODE values onto the
+// go-sql-driver tls parameter; anything else is passed through unchanged.
+func mysqlTLSMode(mode string) string {
+	switch mode {
+	case "", "disable":
+		return "false"
+	case "require":
+		return "skip-verify"
+	case "verify-ca", "verify-full":
+		return "true"
+	default:
+		return mode
+	}
+}
@@ -0,0 +1,66 @@
+package database
+
+import (
+	"database/sql"
+	"sync/atomic"
+)
+
+// Database is the primary pool plus any read replicas. Writes and
+// transactions always use Primary; Reader spreads read-only queries over the
+// healthy replicas.
+type Database struct {
+	Type     string
+	Primary  *sql.DB
+	Replicas []*Replica
+	next     uint32
+}
+
+type Replica struct {
+	Pool   *sql.DB
+	Health *Health
+}
+
+func New(dbType string, primary *sql.DB, replicas ...*Replica) *Database {
+	return &Database{
+		Type:     dbType,
+		Primary:  primary,
+		Replicas: replicas,
+	}
+}
+
+func (d *Database) Writer() *sql.DB {
+	return d.Primary
+}
+
+// Reader returns the next healthy replica in round-robin order, or the
+// primary when there are no healthy replicas.
+func (d *Database) Reader() *sql.DB {
+	n := uint32(len(d.Replicas))
+	if n == 0 {
+		return d.Primary
+	}
+
+	start := atomic.AddUint32(&d.next, 1)
+	for i := uint32(0); i < n; i++ {
+		r := d.Replicas[(start+i)%n]
+		if r.Health == nil || r.Health.Healthy() {
+			return r.Pool
+		}
+	}
+	return d.Primary
+}
+
+// Close stops the replica health checks and closes the replica pools. The
+// primary pool is owned by the caller.
+func (d *Database) Close() error {
+	var firstErr error
+	for _, r := range d.Replicas {
+		if r.Health != nil {
+			_ = r.Health.Stop()
+		}
+		if err := r.Pool.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,80 @@
+package database
+
+import (
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// replica opens an in-memory pool and checks its health once. A closed
+// replica fails the check.
+func replica(t *testing.T, closed bool) *Replica {
+	t.Helper()
+	pool, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+	if closed {
+		_ = pool.Close()
+	}
+
+	health := NewHealth(pool, 0)
+	health.Start()
+	return &Replica{Pool: pool, Health: health}
+}
+
+func TestReaderSkipsUnhealthyReplicas(t *testing.T) {
+	primary, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = primary.Close() })
+
+	a, down, b := replica(t, false), replica(t, true), replica(t, false)
+	if down.Health.Healthy() || down.Health.Err() == nil {
+		t.Fatal("expected the closed replica to be marked unhealthy")
+	}
+	d := New("sqlite", primary, a, down, b)
+
+	counts := make(map[*sql.DB]int)
+	for i := 0; i < 10; i++ {
+		counts[d.Reader()]++
+	}
+	if counts[down.Pool] != 0 || counts[primary] != 0 {
+		t.Errorf("expected reads to skip the unhealthy replica and the primary, got %d and %d", counts[down.Pool], counts[primary])
+	}
+	if counts[a.Pool] == 0 || counts[b.Pool] == 0 {
+		t.Errorf("expected both healthy replicas to serve reads, got %d and %d", counts[a.Pool], counts[b.Pool])
+	}
+	if d.Writer() != primary {
+		t.Error("expected writes to go to the primary")
+	}
+}
+
+func TestReaderFallsBackToPrimary(t *testing.T) {
+	primary, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = primary.Close() })
+
+	if got := New("sqlite", primary).Reader(); got != primary {
+		t.Error("expected the primary without replicas")
+	}
+
+	d := New("sqlite", primary, replica(t, true), replica(t, true))
+	for i := 0; i < 3; i++ {
+		if d.Reader() != primary {
+			t.Fatal("expected the primary when every replica is unhealthy")
+		}
+	}
+
+	// an unchecked replica is not trusted either
+	unchecked := replica(t, false)
+	unchecked.Health = NewHealth(unchecked.Pool, 0)
+	if got := New("sqlite", primary, unchecked).Reader(); got != primary {
+		t.Error("expected the primary while the only replica has not been checked")
+	}
+}
@@ -0,0 +1,64 @@
+// Package dbtest opens throwaway SQLite databases with the tables make auth
+// creates, for tests.
+package dbtest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"myapp/database"
+
+	"github.com/golang-migrate/migrate/v4"
+	_ "github.com/golang-migrate/migrate/v4/database/sqlite3"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Open creates a SQLite database in dir, an empty directory, applies the
+// auth migrations read from the templates under root, and returns it. The
+// caller closes its Primary pool.
+func Open(root, dir string) (*database.Database, error) {
+	src := filepath.Join(dir, "migrations")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		return nil, err
+	}
+	for i, name := range database.AuthMigrations {
+		for _, direction := range []string{"up", "down"} {
+			file := fmt.Sprintf("%s.%s.sql", name, direction)
+			b, err := ioutil.ReadFile(filepath.Join(root, "templates", "migrations", "sqlite", file))
+			if err != nil {
+				return nil, err
+			}
+			if err := ioutil.WriteFile(filepath.Join(src, fmt.Sprintf("%d_%s", i+1, file)), b, 0644); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	file := filepath.Join(dir, "test.db")
+	m, err := migrate.New("file://"+filepath.ToSlash(src), "sqlite3://"+file+"?_foreign_keys=1")
+	if err != nil {
+		return nil, err
+	}
+	err = m.Up()
+	srcErr, dbErr := m.Close()
+	if err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return nil, err
+	}
+	if srcErr != nil {
+		return nil, srcErr
+	}
+	if dbErr != nil {
+		return nil, dbErr
+	}
+
+	pool, err := sql.Open("sqlite3", "file:"+file+"?_foreign_keys=1&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	return database.New("sqlite", pool), nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+// Health pings a pool in the background and keeps the result of the last
+// check together with the pool statistics.
+type Health struct {
+	Pool     *sql.DB
+	Interval time.Duration
+	Timeout  time.Duration
+	OnError  func(error)
+
+	mu        sync.RWMutex
+	err       error
+	checkedAt time.Time
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+func NewHealth(pool *sql.DB, interval time.Duration) *Health {
+	return &Health{
+		Pool:     pool,
+		Interval: interval,
+		Timeout:  5 * time.Second,
+	}
+}
+
+// Start runs the first check immediately and then one every Interval until
+// Stop is called. An Interval of zero or less only runs the first check.
+func (h *Health) Start() {
+	h.Check()
+	if h.Interval <= 0 {
+		return
+	}
+
+	h.stop = make(chan struct{})
+	h.done = make(chan struct{})
+
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(h.Interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+				h.Check()
+			}
+		}
+	}()
+}
+
+func (h *Health) Stop() error {
+	if h.stop == nil {
+		return nil
+	}
+	close(h.stop)
+	<-h.done
+	h.stop = nil
+	return nil
+}
+
+func (h *Health) Check() error {
+	ctx, cancel := context.WithTimeout(context.Background(), h.Timeout)
+	defer cancel()
+
+	err := h.Pool.PingContext(ctx)
+
+	h.mu.Lock()
+	h.err = err
+	h.checkedAt = time.Now()
+	h.mu.Unlock()
+
+	if err != nil && h.OnError != nil {
+		h.OnError(err)
+	}
+	return err
+}
+
+func (h *Health) Healthy() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.err == nil && !h.checkedAt.IsZero()
+}
+
+// Err returns the error of the last check, if it failed.
+func (h *Health) Err() error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.err
+}
+
+func (h *Health) CheckedAt() time.Time {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.checkedAt
+}
+
+func (h *Health) Stats() sql.DBStats {
+	return h.Pool.Stats()
+}
@@ -0,0 +1,14 @@
+package database
+
+// AuthMigrations are the tables make auth creates, in the order they must be
+// applied. Each is a pair of templates/migrations/<dialect>/<name>.up.sql and
+// .down.sql files.
+var AuthMigrations = []string{
+	"create_users_table",
+	"create_remember_tokens_table",
+	"create_password_resets_table",
+	"create_tokens_table",
+	"create_roles_and_permissions_tables",
+	"create_two_factor_tables",
+	"create_user_identities_table",
+}
@@ -0,0 +1,187 @@
+// Package encryption encrypts values stored by the application with AES-GCM,
+// so they cannot be read or altered without the key. Several keys can be
+// loaded at once, which lets KEY be rotated without losing old data.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// version prefixes every ciphertext this package writes. Values without it
+// were written by celeritas.Encryption with AES-CFB, which only Reencrypt
+// reads.
+const version = "v1"
+
+var (
+	ErrMalformed  = errors.New("encryption: malformed ciphertext")
+	ErrUnknownKey = errors.New("encryption: ciphertext was encrypted with an unknown key")
+	ErrDecrypt    = errors.New("encryption: ciphertext was altered or the key is wrong")
+	// ErrLegacy is returned by Decrypt for a value from celeritas.Encryption;
+	// run key:rotate to encrypt such values again.
+	ErrLegacy = errors.New("encryption: legacy ciphertext, run key:rotate")
+	// ErrAmbiguous is returned by Reencrypt for a legacy value that more than
+	// one key decrypts to valid text.
+	ErrAmbiguous = errors.New("encryption: legacy ciphertext is valid under more than one key")
+)
+
+// Encrypter encrypts with its current key and decrypts with any of its keys.
+// Ciphertexts look like
+//
+//	v1.<key id>.<base64 of nonce and sealed text>
+//
+// where the key ID, a short hash of the key, picks the key to decrypt with.
+type Encrypter struct {
+	keys []key
+}
+
+type key struct {
+	id    string
+	block cipher.Block
+	aead  cipher.AEAD
+}
+
+// New returns an Encrypter that encrypts with current and can also decrypt
+// what was encrypted with any of old. Keys must be 16, 24 or 32 bytes long.
+func New(current []byte, old ...[]byte) (*Encrypter, error) {
+	e := &Encrypter{}
+	for i, k := range append([][]byte{current}, old...) {
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, fmt.Errorf("encryption: key %d: %w", i, err)
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		e.keys = append(e.keys, key{id: keyID(k), block: block, aead: aead})
+	}
+	return e, nil
+}
+
+// Encrypt encrypts text with the current key.
+func (e *Encrypter) Encrypt(text string) (string, error) {
+	k := e.keys[0]
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(text)+k.aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("encryption: reading nonce: %w", err)
+	}
+
+	header := version + "." + k.id
+	sealed := k.aead.Seal(nonce, nonce, []byte(text), []byte(header))
+	return header + "." + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the text in ciphertext. Only authenticated values are
+// accepted; a legacy AES-CFB value is refused with ErrLegacy.
+func (e *Encrypter) Decrypt(ciphertext string) (string, error) {
+	if !strings.HasPrefix(ciphertext, version+".") {
+		return "", ErrLegacy
+	}
+
+	parts := strings.SplitN(ciphertext, ".", 3)
+	if len(parts) != 3 {
+		return "", ErrMalformed
+	}
+	k, ok := e.key(parts[1])
+	if !ok {
+		return "", ErrUnknownKey
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(sealed) < k.aead.NonceSize()+k.aead.Overhead() {
+		return "", ErrMalformed
+	}
+
+	nonce, sealed := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+	text, err := k.aead.Open(nil, nonce, sealed, []byte(parts[0]+"."+parts[1]))
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(text), nil
+}
+
+// IsCurrent reports whether ciphertext was encrypted by this package with
+// the current key, so it does not need encrypting again.
+func (e *Encrypter) IsCurrent(ciphertext string) bool {
+	return strings.HasPrefix(ciphertext, version+"."+e.keys[0].id+".")
+}
+
+// Reencrypt encrypts ciphertext again with the current key, unless it
+// already is. changed reports whether it did. It is the migration path for
+// legacy AES-CFB values, so it also reads those; nothing else should, as
+// they are not authenticated. valid tells a legacy value decrypted with the
+// right key from the noise a wrong key gives, so it should only accept text
+// of the expected form. Without it, legacy values are refused with ErrLegacy.
+func (e *Encrypter) Reencrypt(ciphertext string, valid func(text string) bool) (out string, changed bool, err error) {
+	if e.IsCurrent(ciphertext) {
+		return ciphertext, false, nil
+	}
+	var text string
+	if strings.HasPrefix(ciphertext, version+".") {
+		text, err = e.Decrypt(ciphertext)
+	} else {
+		text, err = e.decryptLegacy(ciphertext, valid)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	out, err = e.Encrypt(text)
+	if err != nil {
+		return "", false, err
+	}
+	return out, true, nil
+}
+
+func (e *Encrypter) key(id string) (key, bool) {
+	for _, k := range e.keys {
+		if k.id == id {
+			return k, true
+		}
+	}
+	return key{}, false
+}
+
+// decryptLegacy reads the AES-CFB format of celeritas.Encryption. It has no
+// MAC and no key ID, so the key is the only one whose result valid accepts,
+// and an altered value goes unnoticed unless valid catches it.
+func (e *Encrypter) decryptLegacy(ciphertext string, valid func(string) bool) (string, error) {
+	if valid == nil {
+		return "", ErrLegacy
+	}
+	raw, err := base64.URLEncoding.DecodeString(ciphertext)
+	if err != nil || len(raw) < aes.BlockSize {
+		return "", ErrMalformed
+	}
+	iv, raw := raw[:aes.BlockSize], raw[aes.BlockSize:]
+
+	var found []string
+	for _, k := range e.keys {
+		text := make([]byte, len(raw))
+		cipher.NewCFBDecrypter(k.block, iv).XORKeyStream(text, raw)
+		if valid(string(text)) {
+			found = append(found, string(text))
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", ErrDecrypt
+	case 1:
+		return found[0], nil
+	default:
+		return "", ErrAmbiguous
+	}
+}
+
+// keyID names a key in ciphertexts without giving anything away about it.
+func keyID(k []byte) string {
+	sum := sha256.Sum256(append([]byte("encryption key id:"), k...))
+	return hex.EncodeToString(sum[:4])
+}
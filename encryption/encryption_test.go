@@ -0,0 +1,115 @@
+package encryption
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/lozhkindm/celeritas"
+)
+
+var (
+	testKey = []byte("0123456789abcdef0123456789abcdef")
+	oldKey  = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	e, err := New(testKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := e.Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, err := e.Decrypt(ciphertext); err != nil || text != "secret" {
+		t.Errorf("expected %q, got %q, %v", "secret", text, err)
+	}
+
+	tampered := []byte(ciphertext)
+	tampered[len(tampered)-2] ^= 1
+	if _, err := e.Decrypt(string(tampered)); !errors.Is(err, ErrDecrypt) && !errors.Is(err, ErrMalformed) {
+		t.Errorf("expected an altered value to be refused, got %v", err)
+	}
+
+	old, _ := New(oldKey)
+	fromOld, _ := old.Encrypt("secret")
+	if text, err := e.Decrypt(fromOld); err != nil || text != "secret" {
+		t.Errorf("expected a value from the old key to decrypt, got %q, %v", text, err)
+	}
+}
+
+// lowercase accepts the plain text the legacy tests encrypt.
+func lowercase(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, r := range text {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
+
+func TestLegacyValuesOnlyReadByReencrypt(t *testing.T) {
+	legacy, err := (&celeritas.Encryption{Key: testKey}).Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := New(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Decrypt(legacy); !errors.Is(err, ErrLegacy) {
+		t.Errorf("expected Decrypt to refuse a legacy value, got %v", err)
+	}
+	if _, _, err := e.Reencrypt(legacy, nil); !errors.Is(err, ErrLegacy) {
+		t.Errorf("expected Reencrypt to refuse a legacy value without a check, got %v", err)
+	}
+
+	out, changed, err := e.Reencrypt(legacy, lowercase)
+	if err != nil || !changed || !strings.HasPrefix(out, version+".") {
+		t.Fatalf("expected Reencrypt to migrate a legacy value, got %q, %v, %v", out, changed, err)
+	}
+	if text, err := e.Decrypt(out); err != nil || text != "secret" {
+		t.Errorf("expected %q after Reencrypt, got %q, %v", "secret", text, err)
+	}
+	if _, changed, _ := e.Reencrypt(out, nil); changed {
+		t.Error("expected a current value to be left alone")
+	}
+}
+
+func TestReencryptPicksLegacyKeyByCheck(t *testing.T) {
+	legacy, err := (&celeritas.Encryption{Key: oldKey}).Encrypt("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the current key is tried first, and its noise is refused
+	e, err := New(testKey, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _, err := e.Reencrypt(legacy, lowercase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, err := e.Decrypt(out); err != nil || text != "secret" {
+		t.Errorf("expected the old key to be used, got %q, %v", text, err)
+	}
+
+	without, err := New(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := without.Reencrypt(legacy, lowercase); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("expected ErrDecrypt when no key gives valid text, got %v", err)
+	}
+
+	anything := func(string) bool { return true }
+	if _, _, err := e.Reencrypt(legacy, anything); !errors.Is(err, ErrAmbiguous) {
+		t.Errorf("expected ErrAmbiguous when every key passes the check, got %v", err)
+	}
+}
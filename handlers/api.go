@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"myapp/apierror"
+	"myapp/data"
+	"myapp/validation"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	defaultTokenTTL = 30 * 24 * time.Hour
+	maxTokenTTL     = 365 * 24 * time.Hour
+)
+
+type issueTokenRequest struct {
+	Email     string   `json:"email" validate:"required,email"`
+	Password  string   `json:"password" validate:"required"`
+	Code      string   `json:"code"`
+	Name      string   `json:"name" validate:"max=255"`
+	Scopes    []string `json:"scopes"`
+	ExpiresIn int      `json:"expires_in" validate:"min=0"`
+}
+
+// IssueToken exchanges an email and password for an API token. expires_in is
+// in seconds and defaults to 30 days. Users with 2FA enabled must also send a
+// code from their authenticator app, or a recovery code.
+func (h *Handlers) IssueToken(w http.ResponseWriter, r *http.Request) {
+	var req issueTokenRequest
+	if err := h.App.ReadJSON(w, r, &req); err != nil {
+		apierror.Write(h.App, w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	v := validation.New(nil)
+	v.Locale = h.translator(r).Locale
+	v.Struct(&req)
+	if !v.IsValid() {
+		h.validationErrorJSON(w, v)
+		return
+	}
+
+	user, err := h.Models.Users.GetByEmail(req.Email)
+	if err != nil || user.Active == 0 {
+		apierror.Write(h.App, w, http.StatusUnauthorized, "invalid email or password")
+		return
+	}
+	matches, err := user.PasswordMatches(req.Password)
+	if err != nil {
+		h.App.ErrorLog.Println("error checking password:", err)
+		apierror.Write(h.App, w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if !matches {
+		apierror.Write(h.App, w, http.StatusUnauthorized, "invalid email or password")
+		return
+	}
+
+	enrolment, err := h.Models.TwoFactors.GetForUser(user.ID)
+	if err != nil {
+		h.App.ErrorLog.Println("error checking two-factor:", err)
+		apierror.Write(h.App, w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if enrolment != nil && enrolment.Enabled != 0 {
+		if req.Code == "" {
+			apierror.Write(h.App, w, http.StatusUnauthorized, "two-factor code required")
+			return
+		}
+		if enrolment.LockedAt(h.now()) {
+			h.tooManyCodes(w, enrolment.LockedUntil)
+			return
+		}
+		if !h.checkTwoFactorAPI(w, user.ID, req.Code) {
+			return
+		}
+	}
+
+	ttl := defaultTokenTTL
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+	if ttl > maxTokenTTL {
+		ttl = maxTokenTTL
+	}
+
+	token, err := h.Models.Tokens.Issue(user.ID, req.Name, ttl, req.Scopes)
+	if err != nil {
+		h.App.ErrorLog.Println("error issuing token:", err)
+		apierror.Write(h.App, w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	payload := struct {
+		ID        int       `json:"id"`
+		Token     string    `json:"token"`
+		Scopes    []string  `json:"scopes"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}{token.ID, token.PlainText, token.ScopeList(), token.ExpiresAt}
+
+	if err := h.App.WriteJSON(w, http.StatusCreated, payload); err != nil {
+		h.App.ErrorLog.Println("error writing json:", err)
+	}
+}
+
+// checkTwoFactorAPI checks code for userID and answers the request when it is
+// wrong. As each request carries the password, a session cannot count the
+// attempts as it does for the login form, so the enrolment counts them: after
+// maxTwoFactorAttempts wrong codes in a row no code is checked for
+// twoFactorLockout.
+func (h *Handlers) checkTwoFactorAPI(w http.ResponseWriter, userID int, code string) bool {
+	ok, err := h.checkTwoFactor(userID, code)
+	if err != nil {
+		h.App.ErrorLog.Println("error checking two-factor code:", err)
+		apierror.Write(h.App, w, http.StatusInternalServerError, "internal server error")
+		return false
+	}
+	if ok {
+		if err := h.Models.TwoFactors.ResetFailures(userID); err != nil {
+			h.App.ErrorLog.Println("error resetting two-factor failures:", err)
+		}
+		return true
+	}
+
+	locked, err := h.Models.TwoFactors.Fail(userID, maxTwoFactorAttempts, twoFactorLockout, h.now())
+	if err != nil {
+		h.App.ErrorLog.Println("error counting two-factor failures:", err)
+		apierror.Write(h.App, w, http.StatusInternalServerError, "internal server error")
+		return false
+	}
+	if locked {
+		h.tooManyCodes(w, h.now().Add(twoFactorLockout).Unix())
+		return false
+	}
+	apierror.Write(h.App, w, http.StatusUnauthorized, "invalid two-factor code")
+	return false
+}
+
+// tooManyCodes refuses a code while the enrolment is locked until the Unix
+// time until.
+func (h *Handlers) tooManyCodes(w http.ResponseWriter, until int64) {
+	retry := until - h.now().Unix()
+	if retry < 1 {
+		retry = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(retry, 10))
+	apierror.Write(h.App, w, http.StatusTooManyRequests, "too many invalid two-factor codes, try again later")
+}
+
+// RevokeToken revokes the token the request was made with, or, when the URL
+// has an ID, another token of the same user.
+func (h *Handlers) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	user := data.UserFromContext(r.Context())
+	token := data.TokenFromContext(r.Context())
+
+	if param := chi.URLParam(r, "id"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil {
+			apierror.Write(h.App, w, http.StatusNotFound, "token not found")
+			return
+		}
+		if token, err = h.Models.Tokens.Get(id); err != nil || token.UserID != user.ID {
+			apierror.Write(h.App, w, http.StatusNotFound, "token not found")
+			return
+		}
+	}
+
+	if err := h.Models.Tokens.Delete(token.ID); err != nil {
+		h.App.ErrorLog.Println("error revoking token:", err)
+		apierror.Write(h.App, w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	if h.App.Cache != nil {
+		if err := h.App.Cache.Forget(token.CacheKey()); err != nil {
+			h.App.ErrorLog.Println("error removing token from cache:", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CurrentUser returns the user the API token belongs to.
+func (h *Handlers) CurrentUser(w http.ResponseWriter, r *http.Request) {
+	if err := h.App.WriteJSON(w, http.StatusOK, data.UserFromContext(r.Context())); err != nil {
+		h.App.ErrorLog.Println("error writing json:", err)
+	}
+}
+
+// validationErrorJSON answers a request that failed validation with the
+// message for each invalid field.
+func (h *Handlers) validationErrorJSON(w http.ResponseWriter, v *validation.Validation) {
+	apierror.WriteResponse(h.App, w, http.StatusUnprocessableEntity, apierror.Response{
+		Error:   true,
+		Message: "invalid request",
+		Errors:  v.Errors,
+	})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/validation"
+)
+
+// issueToken posts a token request with code to IssueToken.
+func issueToken(t *testing.T, email, code string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, err := json.Marshal(map[string]string{"email": email, "password": "password", "code": code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/tokens", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	return serve(testHandlers.IssueToken, req)
+}
+
+func TestIssueTokenLocksTwoFactorAfterWrongCodes(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	setClock(t, &now)
+	userID, otp := enrolTwoFactor(t, "api-2fa@example.com", now.Add(-time.Hour))
+	t.Cleanup(func() {
+		_, _ = testHandlers.Models.Tokens.DeleteForUser(userID)
+	})
+	wrong := otp.Code(now.Add(time.Hour))
+
+	// a right code starts the count again
+	for i := 1; i < maxTwoFactorAttempts; i++ {
+		if rr := issueToken(t, "api-2fa@example.com", wrong); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status %d for wrong code %d, got %d", http.StatusUnauthorized, i, rr.Code)
+		}
+	}
+	if rr := issueToken(t, "api-2fa@example.com", otp.Code(now)); rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d for the right code, got %d", http.StatusCreated, rr.Code)
+	}
+
+	for i := 1; i < maxTwoFactorAttempts; i++ {
+		if rr := issueToken(t, "api-2fa@example.com", wrong); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status %d for wrong code %d, got %d", http.StatusUnauthorized, i, rr.Code)
+		}
+	}
+	rr := issueToken(t, "api-2fa@example.com", wrong)
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d once %d codes in a row were wrong, got %d", http.StatusTooManyRequests, maxTwoFactorAttempts, rr.Code)
+	}
+	if rr.Header().Get("Retry-After") != "900" {
+		t.Errorf("expected to be told to retry after 900 seconds, got %q", rr.Header().Get("Retry-After"))
+	}
+
+	now = now.Add(twoFactorLockout - time.Minute)
+	if rr := issueToken(t, "api-2fa@example.com", otp.Code(now)); rr.Code != http.StatusTooManyRequests {
+		t.Errorf("expected even the right code to be refused while locked, got %d", rr.Code)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if rr := issueToken(t, "api-2fa@example.com", otp.Code(now)); rr.Code != http.StatusCreated {
+		t.Errorf("expected the right code to be accepted after the lockout, got %d", rr.Code)
+	}
+}
+
+func TestIssueTokenRejectsInvalidBodies(t *testing.T) {
+	messages := validation.New(nil)
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		errors map[string]string
+	}{
+		{"malformed json", `{"email": `, http.StatusBadRequest, nil},
+		{"two json values", `{"email": "a@example.com"} {}`, http.StatusBadRequest, nil},
+		{"wrong type", `{"email": "a@example.com", "password": "x", "expires_in": "soon"}`, http.StatusBadRequest, nil},
+		{"invalid fields", `{"email": "someone", "expires_in": -1}`, http.StatusUnprocessableEntity, map[string]string{
+			"email":      messages.Message("email", "email", ""),
+			"password":   messages.Message("required", "password", ""),
+			"expires_in": messages.Message("min", "expires_in", "0"),
+		}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/tokens", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		rr := serve(testHandlers.IssueToken, req)
+		if rr.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, rr.Code)
+			continue
+		}
+
+		var res struct {
+			Error  bool              `json:"error"`
+			Errors map[string]string `json:"errors"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil || !res.Error {
+			t.Errorf("%s: expected an error response, got %v", tt.name, err)
+		}
+		for field, msg := range tt.errors {
+			if res.Errors[field] != msg {
+				t.Errorf("%s: expected %q for %s, got %q", tt.name, msg, field, res.Errors[field])
+			}
+		}
+	}
+}
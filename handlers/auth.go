@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"myapp/data"
+	"myapp/validation"
+)
+
+type loginForm struct {
+	Email    string `form:"email" validate:"required,email"`
+	Password string `form:"password" validate:"required"`
+	Remember string `form:"remember"`
+}
+
+func (h *Handlers) UserLogin(w http.ResponseWriter, r *http.Request) {
+	defer h.App.LoadTime(time.Now())
+	h.renderLogin(w, r, nil)
+}
+
+// renderLogin shows the login form, with the errors of v when it was sent
+// incomplete.
+func (h *Handlers) renderLogin(w http.ResponseWriter, r *http.Request, v *validation.Validation) {
+	td := h.templateData(r)
+	td.Data = map[string]interface{}{
+		"providers":  h.providerNames(),
+		"validation": v,
+		"email":      formValue(v, "email"),
+	}
+	if err := h.render(w, r, "login", nil, td); err != nil {
+		h.App.ErrorLog.Println("error rendering:", err)
+	}
+}
+
+func (h *Handlers) PostUserLogin(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.App.BadRequest(w)
+		return
+	}
+
+	var form loginForm
+	v := h.validator(r)
+	v.Bind(&form)
+	if !v.IsValid() {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		h.renderLogin(w, r, v)
+		return
+	}
+
+	user, err := h.Models.Users.GetByEmail(form.Email)
+	if err != nil || user.Active == 0 {
+		h.loginFailed(w, r)
+		return
+	}
+
+	matches, err := user.PasswordMatches(form.Password)
+	if err != nil {
+		h.App.ErrorLog.Println("error checking password:", err)
+		h.App.InternalError(w)
+		return
+	}
+	if !matches {
+		h.loginFailed(w, r)
+		return
+	}
+
+	h.completeLogin(w, r, user.ID, form.Remember == "remember")
+}
+
+// completeLogin logs in a user who proved who they are, or asks for their
+// second factor first when they have 2FA enabled.
+func (h *Handlers) completeLogin(w http.ResponseWriter, r *http.Request, userID int, remember bool) {
+	enabled, err := h.Models.TwoFactors.EnabledFor(userID)
+	if err != nil {
+		h.App.ErrorLog.Println("error checking two-factor:", err)
+		h.App.InternalError(w)
+		return
+	}
+	if enabled {
+		h.startTwoFactor(w, r, userID, remember)
+		return
+	}
+
+	h.logIn(w, r, userID, remember)
+}
+
+// logIn starts an authenticated session for userID and sends the user home.
+func (h *Handlers) logIn(w http.ResponseWriter, r *http.Request, userID int, remember bool) {
+	if err := h.sessionRenew(r.Context()); err != nil {
+		h.App.ErrorLog.Println("error renewing session:", err)
+		h.App.InternalError(w)
+		return
+	}
+	h.sessionPut(r.Context(), "userID", userID)
+
+	if remember {
+		token := h.randomString(32)
+		if err := h.Models.RememberTokens.InsertToken(userID, token); err != nil {
+			h.App.ErrorLog.Println("error saving remember token:", err)
+		} else if err := h.setRememberCookie(w, userID, token); err != nil {
+			h.App.ErrorLog.Println("error setting remember cookie:", err)
+		} else {
+			h.sessionPut(r.Context(), "remember_token", token)
+		}
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (h *Handlers) Logout(w http.ResponseWriter, r *http.Request) {
+	if token := h.sessionGetString(r.Context(), "remember_token"); token != "" {
+		if err := h.Models.RememberTokens.Delete(token); err != nil {
+			h.App.ErrorLog.Println("error deleting remember token:", err)
+		}
+	}
+	h.Cookies.Delete(w, h.App.GetRememberMeCookieName())
+
+	h.sessionRemove(r.Context(), "userID")
+	h.sessionRemove(r.Context(), "remember_token")
+	h.clearTwoFactor(r)
+	if err := h.sessionRenew(r.Context()); err != nil {
+		h.App.ErrorLog.Println("error renewing session:", err)
+	}
+
+	http.Redirect(w, r, "/users/login", http.StatusSeeOther)
+}
+
+// setRememberCookie stores the remember-me token encrypted, so it cannot be
+// read from the browser or changed to another user's ID.
+func (h *Handlers) setRememberCookie(w http.ResponseWriter, userID int, token string) error {
+	value := fmt.Sprintf("%d|%s", userID, token)
+	return h.Cookies.SetEncrypted(w, h.App.GetRememberMeCookieName(), value, data.RememberTokenLifetime)
+}
+
+// loginFailed gives the same answer for an unknown email, an inactive user
+// and a wrong password, so the form cannot be used to find accounts.
+func (h *Handlers) loginFailed(w http.ResponseWriter, r *http.Request) {
+	h.flashError(r, "auth.login_failed")
+	http.Redirect(w, r, "/users/login", http.StatusSeeOther)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"myapp/validation"
+)
+
+// postForm posts form to h, sending cookies along.
+func postForm(h http.HandlerFunc, target string, form url.Values, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	return serve(h, req)
+}
+
+func TestPostUserLoginShowsFieldErrors(t *testing.T) {
+	// as initApplication does for every locale
+	validation.SetMessages("en", testHandlers.Lang.Messages("en", "validation."))
+
+	rr := postForm(testHandlers.PostUserLogin, "/users/login", url.Values{"email": {"not an email"}}, nil)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+	}
+
+	body := rr.Body.String()
+	for _, want := range []string{
+		`value="not an email"`,
+		"Field &#39;Email&#39; must be a valid email address",
+		"Field &#39;Password&#39; is required",
+		"is-invalid",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the form to contain %q", want)
+		}
+	}
+}
+
+func TestPostUserLoginFlashesTranslatedError(t *testing.T) {
+	form := url.Values{"email": {"nobody@example.com"}, "password": {"password"}}
+	rr := postForm(testHandlers.PostUserLogin, "/users/login", form, nil)
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/users/login" {
+		t.Fatalf("expected a redirect to the login page, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/users/login", nil)
+	for _, c := range rr.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	rr = serve(testHandlers.UserLogin, req)
+
+	want := testHandlers.Lang.Translator("en").T("auth.login_failed")
+	if want == "auth.login_failed" {
+		t.Fatal("expected lang/en.json to translate auth.login_failed")
+	}
+	if !strings.Contains(rr.Body.String(), want) {
+		t.Errorf("expected the login page to show %q", want)
+	}
+	if strings.Contains(rr.Body.String(), "is-invalid") {
+		t.Error("expected no field errors before the form is sent")
+	}
+}
+
+func TestPostResetPasswordShowsFieldErrors(t *testing.T) {
+	form := url.Values{"password": {"short"}, "verify_password": {"other"}}
+	rr := postForm(testHandlers.PostResetPassword, "/users/reset-password?token=unused", form, nil)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+	}
+
+	body := rr.Body.String()
+	for _, field := range []string{"password", "verify_password"} {
+		if !strings.Contains(body, `form-control is-invalid" id="`+field+`"`) {
+			t.Errorf("expected %s to be marked invalid", field)
+		}
+	}
+	if strings.Count(body, `class="invalid-feedback d-block"`) != 2 {
+		t.Errorf("expected an error for each field, got %s", body)
+	}
+}
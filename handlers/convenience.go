@@ -2,15 +2,119 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/lozhkindm/celeritas"
+	"myapp/i18n"
+	"myapp/urlsigner"
+	"myapp/validation"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/lozhkindm/celeritas/mailer"
+	"github.com/lozhkindm/celeritas/render"
 )
 
 func (h *Handlers) render(w http.ResponseWriter, r *http.Request, tmpl string, vars, data interface{}) error {
+	if strings.EqualFold(h.App.Render.Renderer, "jet") {
+		vars = h.jetVars(r, vars)
+	} else {
+		data = h.goData(r, data)
+	}
 	return h.App.Render.Page(w, r, tmpl, vars, data)
 }
 
+// jetVars adds the per-request functions every Jet view can use:
+//
+//	{{if can("posts.edit")}}...{{end}}
+//	{{t("inbox.messages", "count", count)}}
+//	{{formatDate(post.CreatedAt, "long")}} {{formatNumber(price, 2)}}
+//	<html lang="{{locale}}">
+func (h *Handlers) jetVars(r *http.Request, vars interface{}) jet.VarMap {
+	vm, ok := vars.(jet.VarMap)
+	if !ok {
+		vm = make(jet.VarMap)
+	}
+
+	userID := h.App.Session.GetInt(r.Context(), "userID")
+	vm.Set("can", func(permission string) bool {
+		if userID == 0 {
+			return false
+		}
+		allowed, err := h.Models.Permissions.UserHas(userID, permission)
+		if err != nil {
+			h.App.ErrorLog.Println("error checking permission:", err)
+			return false
+		}
+		return allowed
+	})
+
+	t := h.translator(r)
+	vm.Set("locale", t.Locale)
+	vm.Set("t", t.T)
+	vm.Set("formatDate", t.Date)
+	vm.Set("formatNumber", t.Number)
+	return vm
+}
+
+// goData gives Go templates the request's translator as .Data.lang, as in
+// {{.Data.lang.T "inbox.title"}}; the celeritas renderer takes no template
+// functions.
+func (h *Handlers) goData(r *http.Request, data interface{}) interface{} {
+	td, ok := data.(*render.TemplateData)
+	if data == nil {
+		td, ok = &render.TemplateData{}, true
+	}
+	if !ok {
+		return data
+	}
+	if td.Data == nil {
+		td.Data = make(map[string]interface{})
+	}
+	td.Data["lang"] = h.translator(r)
+	return td
+}
+
+// templateData returns the data for a page, with any flash and error message
+// moved out of the session so it is shown once.
+func (h *Handlers) templateData(r *http.Request) *render.TemplateData {
+	return &render.TemplateData{
+		Flash: h.App.Session.PopString(r.Context(), "flash"),
+		Error: h.App.Session.PopString(r.Context(), "error"),
+	}
+}
+
+// flash keeps a message for the next page, translated from key into the
+// request's locale with args as in Translator.T. flashError does the same
+// for an error.
+func (h *Handlers) flash(r *http.Request, key string, args ...interface{}) {
+	h.sessionPut(r.Context(), "flash", h.translator(r).T(key, args...))
+}
+
+func (h *Handlers) flashError(r *http.Request, key string, args ...interface{}) {
+	h.sessionPut(r.Context(), "error", h.translator(r).T(key, args...))
+}
+
+// formValue returns the value submitted for field, to fill a form shown
+// again with errors, or "" when there is no submission.
+func formValue(v *validation.Validation, field string) string {
+	if v == nil {
+		return ""
+	}
+	return v.Data.Get(field)
+}
+
+// validator returns a Validation of the submitted form whose messages are in
+// the request's locale.
+func (h *Handlers) validator(r *http.Request) *validation.Validation {
+	v := validation.New(r.Form)
+	v.Locale = h.translator(r).Locale
+	return v
+}
+
 func (h *Handlers) sessionPut(ctx context.Context, key string, val interface{}) {
 	h.App.Session.Put(ctx, key, val)
 }
@@ -44,19 +148,65 @@ func (h *Handlers) randomString(n int) string {
 }
 
 func (h *Handlers) encrypt(text string) (string, error) {
-	enc := celeritas.Encryption{Key: []byte(h.App.EncryptionKey)}
-	encrypted, err := enc.Encrypt(text)
-	if err != nil {
-		return "", err
-	}
-	return encrypted, nil
+	return h.Encrypter.Encrypt(text)
 }
 
 func (h *Handlers) decrypt(crypto string) (string, error) {
-	enc := celeritas.Encryption{Key: []byte(h.App.EncryptionKey)}
-	text, err := enc.Decrypt(crypto)
-	if err != nil {
-		return "", err
+	return h.Encrypter.Decrypt(crypto)
+}
+
+func (h *Handlers) signURL(rawURL string, ttl time.Duration) (string, error) {
+	signer := urlsigner.Signer{Secret: []byte(h.App.EncryptionKey)}
+	return signer.Sign(rawURL, ttl)
+}
+
+var errNoMailQueue = errors.New("no mail queue set up")
+
+// sendMail queues msg, sent from the configured address unless it sets its
+// own. The SMTP mailer does not fill in the sender by itself. The mail is
+// written in t's locale: its templates can translate with {{.Lang.T "key"}},
+// and a template made for the locale, such as password-reset.de.html.tmpl,
+// is used when there is one.
+func (h *Handlers) sendMail(msg mailer.Message, t *i18n.Translator) error {
+	if h.QueueMail == nil {
+		return errNoMailQueue
+	}
+	if t != nil {
+		msg.Template = h.localizedMail(msg.Template, t.Locale)
+		if data, ok := msg.Data.(map[string]interface{}); ok {
+			data["Lang"] = t
+		}
+	}
+	if msg.From == "" {
+		msg.From = h.App.Mail.FromAddress
+	}
+	if msg.FromName == "" {
+		msg.FromName = h.App.Mail.FromName
+	}
+	h.QueueMail(msg)
+	return nil
+}
+
+// localizedMail returns the name of the mail template for locale, or for its
+// language alone, falling back to name.
+func (h *Handlers) localizedMail(name, locale string) string {
+	candidates := []string{name + "." + locale}
+	if i := strings.Index(locale, "-"); i > 0 {
+		candidates = append(candidates, name+"."+locale[:i])
+	}
+	for _, c := range candidates {
+		if _, err := os.Stat(filepath.Join(h.App.Mail.TemplatesDir, c+".html.tmpl")); err == nil {
+			return c
+		}
+	}
+	return name
+}
+
+// translator returns the translator the Locale middleware picked for r. The
+// API does not run it, so there the Accept-Language header decides.
+func (h *Handlers) translator(r *http.Request) *i18n.Translator {
+	if t := i18n.FromContext(r.Context()); t != nil {
+		return t
 	}
-	return text, nil
+	return h.Lang.Translator(h.Lang.Match(i18n.ParseAcceptLanguage(r.Header.Get("Accept-Language"))...))
 }
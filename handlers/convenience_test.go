@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"myapp/data"
+	"myapp/database"
+
+	"github.com/CloudyKit/jet/v6"
+)
+
+func TestLocalizedMail(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"welcome", "welcome.de", "welcome.pt-BR", "receipt"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".html.tmpl"), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := testHandlers.App.Mail.TemplatesDir
+	testHandlers.App.Mail.TemplatesDir = dir
+	t.Cleanup(func() { testHandlers.App.Mail.TemplatesDir = old })
+
+	tests := []struct {
+		name, locale, want string
+	}{
+		{"welcome", "pt-BR", "welcome.pt-BR"},
+		{"welcome", "de-AT", "welcome.de"},
+		{"welcome", "de", "welcome.de"},
+		{"welcome", "pt-PT", "welcome"},
+		{"welcome", "fr", "welcome"},
+		{"receipt", "de-AT", "receipt"},
+	}
+	for _, tt := range tests {
+		if got := testHandlers.localizedMail(tt.name, tt.locale); got != tt.want {
+			t.Errorf("%s in %s: expected %s, got %s", tt.name, tt.locale, tt.want, got)
+		}
+	}
+}
+
+func TestJetCan(t *testing.T) {
+	userID, err := testHandlers.Models.Users.Insert(data.User{
+		FirstName: "Jet",
+		LastName:  "Editor",
+		Email:     "jet-can@example.com",
+		Password:  "password",
+		Active:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles := testHandlers.Models.Roles
+	if err := roles.Assign(userID, "jet-editor"); err != nil {
+		t.Fatal(err)
+	}
+	if err := roles.Grant("jet-editor", "posts.edit"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = roles.Revoke("jet-editor", "posts.edit")
+		_ = roles.Unassign(userID, "jet-editor")
+		_ = testHandlers.Models.Users.Delete(userID)
+	})
+
+	// can returns the can function of a request logged in as userID
+	can := func(userID int) func(string) bool {
+		var vm jet.VarMap
+		serve(func(w http.ResponseWriter, r *http.Request) {
+			if userID != 0 {
+				testHandlers.App.Session.Put(r.Context(), "userID", userID)
+			}
+			vm = testHandlers.jetVars(r, nil)
+		}, httptest.NewRequest(http.MethodGet, "/", nil))
+		return vm["can"].Interface().(func(string) bool)
+	}
+
+	if !can(userID)("posts.edit") {
+		t.Error("expected a granted permission to be allowed")
+	}
+	if can(userID)("posts.delete") {
+		t.Error("expected a permission that was not granted to be denied")
+	}
+	if can(0)("posts.edit") {
+		t.Error("expected a guest to be denied")
+	}
+
+	// a lookup that fails denies, and is logged
+	empty, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data.New(database.New("sqlite", empty))
+	t.Cleanup(func() {
+		data.New(testDB)
+		_ = empty.Close()
+	})
+	var logged bytes.Buffer
+	errorLog := testHandlers.App.ErrorLog
+	testHandlers.App.ErrorLog = log.New(&logged, "", 0)
+	t.Cleanup(func() { testHandlers.App.ErrorLog = errorLog })
+
+	if can(userID)("posts.edit") {
+		t.Error("expected a failed lookup to deny")
+	}
+	if !strings.Contains(logged.String(), "error checking permission") {
+		t.Errorf("expected the failure to be logged, got %q", logged.String())
+	}
+}
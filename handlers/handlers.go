@@ -4,14 +4,34 @@ import (
 	"net/http"
 	"time"
 
+	"myapp/cache"
+	"myapp/cookies"
 	"myapp/data"
+	"myapp/encryption"
+	"myapp/i18n"
+	"myapp/oauth"
 
 	"github.com/lozhkindm/celeritas"
+	"github.com/lozhkindm/celeritas/mailer"
 )
 
 type Handlers struct {
-	App    *celeritas.Celeritas
-	Models data.Models
+	App       *celeritas.Celeritas
+	Models    data.Models
+	Providers map[string]oauth.Provider
+	Encrypter *encryption.Encrypter
+	Cookies   *cookies.Jar
+	Lang      *i18n.Bundle
+	Cache     *cache.Store
+
+	// Now is the clock two-factor codes and logins are checked against; nil
+	// means time.Now.
+	Now func() time.Time
+
+	// QueueMail hands a message to the mail listener. The application sets
+	// it to keep count of the mail not sent yet, so that shutdown can wait
+	// for it; handlers never write to App.Mail.Jobs themselves.
+	QueueMail func(mailer.Message)
 }
 
 func (h *Handlers) Home(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// localeKey names the session value and cookie the Locale middleware reads
+	localeKey      = "locale"
+	localeLifetime = 365 * 24 * time.Hour
+)
+
+// SetLocale saves the locale a user picked, in the session and in a cookie
+// that outlives it, then sends them back to the page they came from.
+func (h *Handlers) SetLocale(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.App.BadRequest(w)
+		return
+	}
+	locale := h.Lang.Match(r.Form.Get("locale"))
+	if locale == "" {
+		h.App.BadRequest(w)
+		return
+	}
+
+	h.sessionPut(r.Context(), localeKey, locale)
+	h.Cookies.SetSigned(w, localeKey, locale, localeLifetime)
+	http.Redirect(w, r, backTo(r), http.StatusSeeOther)
+}
+
+// backTo returns the path of the referring page on this site, or /.
+func backTo(r *http.Request) string {
+	ref, err := url.Parse(r.Referer())
+	if err != nil || ref.Host != r.Host || ref.Path == "" {
+		return "/"
+	}
+	if ref.RawQuery != "" {
+		return ref.Path + "?" + ref.RawQuery
+	}
+	return ref.Path
+}
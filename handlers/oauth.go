@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"myapp/data"
+	"myapp/oauth"
+
+	"github.com/go-chi/chi/v5"
+	udb "github.com/upper/db/v4"
+)
+
+var (
+	errNoVerifiedEmail = errors.New("identity has no verified email")
+	errInactiveUser    = errors.New("user is not active")
+)
+
+// providerNames returns the names of the configured identity providers, for
+// the buttons on the login page.
+func (h *Handlers) providerNames() []string {
+	names := make([]string, 0, len(h.Providers))
+	for name := range h.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OAuthLogin sends the user to the identity provider named in the URL. The
+// state and nonce that must come back are kept in the session.
+func (h *Handlers) OAuthLogin(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.Providers[chi.URLParam(r, "provider")]
+	if !ok {
+		h.App.NotFound(w)
+		return
+	}
+
+	state, err := oauth.RandomString()
+	if err != nil {
+		h.App.ErrorLog.Println("error generating state:", err)
+		h.App.InternalError(w)
+		return
+	}
+	nonce, err := oauth.RandomString()
+	if err != nil {
+		h.App.ErrorLog.Println("error generating nonce:", err)
+		h.App.InternalError(w)
+		return
+	}
+
+	authURL, err := provider.AuthURL(r.Context(), state, nonce)
+	if err != nil {
+		h.App.ErrorLog.Println("error starting oauth login:", err)
+		h.oauthFailed(w, r, "auth.provider_unreachable", provider)
+		return
+	}
+
+	h.sessionPut(r.Context(), "oauth_provider", provider.Name())
+	h.sessionPut(r.Context(), "oauth_state", state)
+	h.sessionPut(r.Context(), "oauth_nonce", nonce)
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OAuthCallback is where the provider sends the user back. The account at
+// the provider is linked to a user the first time: the user with the same
+// verified email, or a new one.
+func (h *Handlers) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.Providers[chi.URLParam(r, "provider")]
+	if !ok {
+		h.App.NotFound(w)
+		return
+	}
+
+	expected := h.sessionGetString(r.Context(), "oauth_provider")
+	state := h.sessionGetString(r.Context(), "oauth_state")
+	nonce := h.sessionGetString(r.Context(), "oauth_nonce")
+	h.sessionRemove(r.Context(), "oauth_provider")
+	h.sessionRemove(r.Context(), "oauth_state")
+	h.sessionRemove(r.Context(), "oauth_nonce")
+
+	q := r.URL.Query()
+	if q.Get("error") != "" {
+		h.oauthFailed(w, r, "auth.provider_cancelled", provider)
+		return
+	}
+	if state == "" || expected != provider.Name() || subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(state)) != 1 {
+		h.oauthFailed(w, r, "auth.login_expired", provider)
+		return
+	}
+
+	identity, err := provider.Identify(r.Context(), q.Get("code"), nonce)
+	if err != nil {
+		h.App.ErrorLog.Println("error identifying oauth user:", err)
+		h.oauthFailed(w, r, "auth.provider_failed", provider)
+		return
+	}
+
+	user, err := h.oauthUser(identity)
+	switch {
+	case errors.Is(err, errNoVerifiedEmail):
+		h.oauthFailed(w, r, "auth.provider_unverified", provider)
+		return
+	case errors.Is(err, errInactiveUser):
+		h.oauthFailed(w, r, "auth.provider_failed", provider)
+		return
+	case err != nil:
+		h.App.ErrorLog.Println("error linking oauth user:", err)
+		h.App.InternalError(w)
+		return
+	}
+
+	h.completeLogin(w, r, user.ID, false)
+}
+
+// oauthUser returns the user linked to identity, linking or creating one on
+// the first login. Users created here get a random password; they can set
+// one with the forgot password form.
+func (h *Handlers) oauthUser(identity *oauth.Identity) (*data.User, error) {
+	link, err := h.Models.UserIdentities.GetByProvider(identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	var user *data.User
+	if link != nil {
+		if user, err = h.Models.Users.GetFromPrimary(link.UserID); err != nil {
+			return nil, err
+		}
+	} else {
+		// an unverified address could belong to someone else, so it is never
+		// used to find, or create, an account
+		if identity.Email == "" || !identity.EmailVerified {
+			return nil, errNoVerifiedEmail
+		}
+
+		user, err = h.Models.Users.GetByEmail(identity.Email)
+		if errors.Is(err, udb.ErrNoMoreRows) {
+			user, err = h.createOAuthUser(identity)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = h.Models.UserIdentities.Insert(data.UserIdentity{
+			UserID:   user.ID,
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if user.Active == 0 {
+		return nil, errInactiveUser
+	}
+	return user, nil
+}
+
+func (h *Handlers) createOAuthUser(identity *oauth.Identity) (*data.User, error) {
+	first, last := identity.Name, ""
+	if i := strings.LastIndex(identity.Name, " "); i > 0 {
+		first, last = identity.Name[:i], identity.Name[i+1:]
+	}
+
+	id, err := h.Models.Users.Insert(data.User{
+		FirstName: first,
+		LastName:  last,
+		Email:     identity.Email,
+		Password:  h.randomString(32),
+		Active:    1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h.Models.Users.GetFromPrimary(id)
+}
+
+// oauthFailed sends the user back to the login page with the message key,
+// which can name the provider as {provider}.
+func (h *Handlers) oauthFailed(w http.ResponseWriter, r *http.Request, key string, provider oauth.Provider) {
+	h.flashError(r, key, "provider", provider.Name())
+	http.Redirect(w, r, "/users/login", http.StatusSeeOther)
+}
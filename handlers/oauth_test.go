@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"myapp/oauth"
+
+	"github.com/go-chi/chi/v5"
+	udb "github.com/upper/db/v4"
+)
+
+// stubProvider answers Identify with identity, and records the nonce it was
+// asked to check.
+type stubProvider struct {
+	identity *oauth.Identity
+	nonce    string
+	called   bool
+}
+
+func (p *stubProvider) Name() string {
+	return "stub"
+}
+
+func (p *stubProvider) AuthURL(_ context.Context, state, nonce string) (string, error) {
+	return "https://provider.example.com/authorize?" + url.Values{"state": {state}, "nonce": {nonce}}.Encode(), nil
+}
+
+func (p *stubProvider) Identify(_ context.Context, _, nonce string) (*oauth.Identity, error) {
+	p.called = true
+	p.nonce = nonce
+	return p.identity, nil
+}
+
+// oauthLogin starts a login with the stub provider and returns the query of
+// the URL the user was sent to, and the session cookie.
+func oauthLogin(t *testing.T, router http.Handler) (url.Values, []*http.Cookie) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth/stub", nil))
+	if rr.Code != http.StatusFound {
+		t.Fatalf("expected status %d, got %d", http.StatusFound, rr.Code)
+	}
+	u, err := url.Parse(rr.Header().Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u.Query(), rr.Result().Cookies()
+}
+
+// oauthCallback comes back from the provider with query.
+func oauthCallback(router http.Handler, query url.Values, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/auth/stub/callback?"+query.Encode(), nil)
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	return rr
+}
+
+func oauthRouter(p oauth.Provider) http.Handler {
+	testHandlers.Providers = map[string]oauth.Provider{p.Name(): p}
+	r := chi.NewRouter()
+	r.Use(testHandlers.App.Session.LoadAndSave)
+	r.Get("/auth/{provider}", testHandlers.OAuthLogin)
+	r.Get("/auth/{provider}/callback", testHandlers.OAuthCallback)
+	return r
+}
+
+func TestOAuthCallbackRejectsWrongState(t *testing.T) {
+	p := &stubProvider{identity: &oauth.Identity{Provider: "stub", Subject: "1", Email: "state@example.com", EmailVerified: true}}
+	router := oauthRouter(p)
+
+	_, cookies := oauthLogin(t, router)
+	rr := oauthCallback(router, url.Values{"code": {"code"}, "state": {"forged"}}, cookies)
+
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/users/login" {
+		t.Errorf("expected a redirect to the login page, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+	if p.called {
+		t.Error("expected the provider not to be asked for the identity")
+	}
+
+	// the state is single use, so the right one fails once it was tried
+	query, cookies := oauthLogin(t, router)
+	oauthCallback(router, url.Values{"code": {"code"}, "state": {"forged"}}, cookies)
+	rr = oauthCallback(router, url.Values{"code": {"code"}, "state": {query.Get("state")}}, cookies)
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/users/login" || p.called {
+		t.Errorf("expected a replayed state to be refused, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+}
+
+func TestOAuthCallbackRefusesUnverifiedEmail(t *testing.T) {
+	p := &stubProvider{identity: &oauth.Identity{Provider: "stub", Subject: "2", Email: "unverified@example.com"}}
+	router := oauthRouter(p)
+
+	query, cookies := oauthLogin(t, router)
+	rr := oauthCallback(router, url.Values{"code": {"code"}, "state": {query.Get("state")}}, cookies)
+
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/users/login" {
+		t.Errorf("expected a redirect to the login page, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+	if _, err := testHandlers.Models.Users.GetByEmail("unverified@example.com"); !errors.Is(err, udb.ErrNoMoreRows) {
+		t.Errorf("expected no user to be created, got %v", err)
+	}
+}
+
+func TestOAuthCallbackCreatesAndLinksUser(t *testing.T) {
+	p := &stubProvider{identity: &oauth.Identity{Provider: "stub", Subject: "3", Email: "new@example.com", EmailVerified: true, Name: "New User"}}
+	router := oauthRouter(p)
+
+	for i := 0; i < 2; i++ {
+		query, cookies := oauthLogin(t, router)
+		rr := oauthCallback(router, url.Values{"code": {"code"}, "state": {query.Get("state")}}, cookies)
+
+		if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/" {
+			t.Fatalf("expected a redirect home, got %d %s", rr.Code, rr.Header().Get("Location"))
+		}
+		if p.nonce != query.Get("nonce") {
+			t.Errorf("expected the provider to check nonce %q, got %q", query.Get("nonce"), p.nonce)
+		}
+	}
+
+	user, err := testHandlers.Models.Users.GetByEmail("new@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.FirstName != "New" || user.LastName != "User" {
+		t.Errorf("expected the user to be named after the identity, got %q %q", user.FirstName, user.LastName)
+	}
+	links, err := testHandlers.Models.UserIdentities.GetForUser(user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links[0].Subject != "3" {
+		t.Errorf("expected one link to subject 3, got %+v", links)
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"myapp/data"
+	"myapp/i18n"
+	"myapp/validation"
+
+	"github.com/lozhkindm/celeritas/mailer"
+)
+
+const minPasswordLength = 8
+
+type forgotForm struct {
+	Email string `form:"email" validate:"required,email"`
+}
+
+type resetPasswordForm struct {
+	Password       string `form:"password" validate:"required"`
+	VerifyPassword string `form:"verify_password" validate:"required"`
+}
+
+func (h *Handlers) Forgot(w http.ResponseWriter, r *http.Request) {
+	defer h.App.LoadTime(time.Now())
+	h.renderForm(w, r, "forgot", nil, "email")
+}
+
+// renderForm shows the form in tmpl, with the errors of v when it was sent
+// invalid, and the submitted values of the fields named in keep.
+func (h *Handlers) renderForm(w http.ResponseWriter, r *http.Request, tmpl string, v *validation.Validation, keep ...string) {
+	td := h.templateData(r)
+	td.Data = map[string]interface{}{"validation": v}
+	for _, field := range keep {
+		td.Data[field] = formValue(v, field)
+	}
+	if err := h.render(w, r, tmpl, nil, td); err != nil {
+		h.App.ErrorLog.Println("error rendering:", err)
+	}
+}
+
+// PostForgot mails a reset link when the address belongs to an active user.
+// The answer is the same either way, so the form cannot be used to find
+// accounts.
+func (h *Handlers) PostForgot(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.App.BadRequest(w)
+		return
+	}
+
+	var form forgotForm
+	v := h.validator(r)
+	v.Bind(&form)
+	if !v.IsValid() {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		h.renderForm(w, r, "forgot", v, "email")
+		return
+	}
+
+	user, err := h.Models.Users.GetByEmail(form.Email)
+	if err == nil && user.Active != 0 {
+		if err := h.sendPasswordReset(user, h.translator(r)); err != nil {
+			h.App.ErrorLog.Println("error sending password reset:", err)
+		}
+	}
+
+	h.flash(r, "auth.reset_link_sent")
+	http.Redirect(w, r, "/users/login", http.StatusSeeOther)
+}
+
+// ResetPassword shows the new password form. Its route checks the signature
+// of the link, and the form posts back to the same signed URL.
+func (h *Handlers) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	defer h.App.LoadTime(time.Now())
+
+	if _, err := h.Models.PasswordResets.Validate(r.URL.Query().Get("token")); err != nil {
+		h.passwordResetFailed(w, r, err)
+		return
+	}
+
+	h.renderForm(w, r, "reset-password", nil)
+}
+
+func (h *Handlers) PostResetPassword(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.App.BadRequest(w)
+		return
+	}
+
+	var form resetPasswordForm
+	v := h.validator(r)
+	v.Bind(&form)
+	if form.Password != "" {
+		v.Check(utf8.RuneCountInString(form.Password) >= minPasswordLength, "password",
+			v.Message("min.string", "password", strconv.Itoa(minPasswordLength)))
+	}
+	if form.VerifyPassword != "" {
+		v.Check(form.VerifyPassword == form.Password, "verify_password",
+			v.Message("match", "verify_password", h.translator(r).T("validation.field.password")))
+	}
+	if !v.IsValid() {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		h.renderForm(w, r, "reset-password", v)
+		return
+	}
+
+	userID, err := h.Models.PasswordResets.Consume(r.URL.Query().Get("token"))
+	if err != nil {
+		h.passwordResetFailed(w, r, err)
+		return
+	}
+
+	if err := h.Models.Users.ResetPassword(userID, form.Password); err != nil {
+		h.App.ErrorLog.Println("error resetting password:", err)
+		h.App.InternalError(w)
+		return
+	}
+	if err := h.Models.RememberTokens.DeleteForUser(userID); err != nil {
+		h.App.ErrorLog.Println("error deleting remember tokens:", err)
+	}
+	h.revokeAPITokens(userID)
+
+	h.flash(r, "auth.password_reset")
+	http.Redirect(w, r, "/users/login", http.StatusSeeOther)
+}
+
+// revokeAPITokens deletes the API tokens of userID and takes them out of the
+// cache, where AuthToken would otherwise still accept them for a while.
+func (h *Handlers) revokeAPITokens(userID int) {
+	tokens, err := h.Models.Tokens.DeleteForUser(userID)
+	if err != nil {
+		h.App.ErrorLog.Println("error deleting api tokens:", err)
+		return
+	}
+	if h.App.Cache == nil {
+		return
+	}
+	for _, token := range tokens {
+		if err := h.App.Cache.Forget(token.CacheKey()); err != nil {
+			h.App.ErrorLog.Println("error removing token from cache:", err)
+		}
+	}
+}
+
+func (h *Handlers) sendPasswordReset(user *data.User, t *i18n.Translator) error {
+	token := h.randomString(32)
+	if err := h.Models.PasswordResets.Insert(user.ID, token); err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/users/reset-password?%s", strings.TrimRight(h.App.Server.URL, "/"), url.Values{"token": {token}}.Encode())
+	link, err := h.signURL(link, data.PasswordResetLifetime)
+	if err != nil {
+		return err
+	}
+
+	return h.sendMail(mailer.Message{
+		To:       user.Email,
+		Subject:  t.T("mail.password_reset.subject"),
+		Template: "password-reset",
+		Data: map[string]interface{}{
+			"Name": user.FirstName,
+			"Link": link,
+			// the plain text mail is rendered with html/template too, which
+			// would turn & into &amp;
+			"PlainLink": template.HTML(link),
+			"Minutes":   int(data.PasswordResetLifetime.Minutes()),
+		},
+	}, t)
+}
+
+func (h *Handlers) passwordResetFailed(w http.ResponseWriter, r *http.Request, err error) {
+	if !errors.Is(err, data.ErrInvalidPasswordReset) {
+		h.App.ErrorLog.Println("error checking password reset:", err)
+		h.App.InternalError(w)
+		return
+	}
+	h.flashError(r, "auth.reset_link_invalid")
+	http.Redirect(w, r, "/users/forgot-password", http.StatusSeeOther)
+}
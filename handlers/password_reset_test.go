@@ -0,0 +1,296 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/cache"
+	"myapp/data"
+	"myapp/urlsigner"
+
+	"github.com/lozhkindm/celeritas/mailer"
+)
+
+func TestPostResetPasswordRevokesAPITokens(t *testing.T) {
+	memory := cache.NewMemory(cache.MemoryOptions{})
+	testHandlers.App.Cache = memory
+	t.Cleanup(func() {
+		testHandlers.App.Cache = nil
+		_ = memory.Close()
+	})
+
+	userID, err := testHandlers.Models.Users.Insert(data.User{
+		FirstName: "Reset",
+		LastName:  "User",
+		Email:     "reset@example.com",
+		Password:  "old password",
+		Active:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = testHandlers.Models.Users.Delete(userID)
+	})
+
+	token, err := testHandlers.Models.Tokens.Issue(userID, "cli", time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// as AuthToken leaves it after a request with the token
+	if err := memory.Set(data.TokenCacheKey(token.PlainText), "cached", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := testHandlers.Models.PasswordResets.Insert(userID, "reset-token"); err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{"password": {"new password"}, "verify_password": {"new password"}}
+	req := httptest.NewRequest(http.MethodPost, "/users/reset-password?token=reset-token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := serve(testHandlers.PostResetPassword, req)
+
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/users/login" {
+		t.Fatalf("expected a redirect to the login page, got %d %s", rr.Code, rr.Header().Get("Location"))
+	}
+	if _, _, err := testHandlers.Models.Tokens.GetUserForToken(token.PlainText); !errors.Is(err, data.ErrInvalidToken) {
+		t.Errorf("expected the token to be deleted, got %v", err)
+	}
+	if found, err := memory.Has(data.TokenCacheKey(token.PlainText)); err != nil || found {
+		t.Errorf("expected the token to be removed from the cache, got %v, %v", found, err)
+	}
+}
+
+// smtpMessage is a mail received by smtpServer.
+type smtpMessage struct {
+	From string
+	To   []string
+	Data []byte
+}
+
+// smtpServer stands in for an SMTP server on a local port, accepting every
+// mail without authentication and passing it on to the returned channel.
+func smtpServer(t *testing.T) (int, <-chan smtpMessage) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = l.Close() })
+
+	received := make(chan smtpMessage, 1)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, received)
+		}
+	}()
+	return l.Addr().(*net.TCPAddr).Port, received
+}
+
+func serveSMTP(conn net.Conn, received chan<- smtpMessage) {
+	defer conn.Close()
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost ESMTP")
+
+	var msg smtpMessage
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch verb {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250 localhost")
+		case "MAIL":
+			msg = smtpMessage{From: smtpAddress(line)}
+			_ = tp.PrintfLine("250 OK")
+		case "RCPT":
+			msg.To = append(msg.To, smtpAddress(line))
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			if msg.Data, err = tp.ReadDotBytes(); err != nil {
+				return
+			}
+			received <- msg
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 Bye")
+			return
+		default:
+			_ = tp.PrintfLine("250 OK")
+		}
+	}
+}
+
+func smtpAddress(line string) string {
+	start, end := strings.Index(line, "<"), strings.LastIndex(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+// plainText returns the decoded text/plain part of a raw mail.
+func plainText(t *testing.T, raw []byte) string {
+	t.Helper()
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, ok := findPlainText(t, textproto.MIMEHeader(msg.Header), msg.Body)
+	if !ok {
+		t.Fatal("expected the mail to have a text/plain part")
+	}
+	return text
+}
+
+func findPlainText(t *testing.T, header textproto.MIMEHeader, body io.Reader) (string, bool) {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return "", false
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text, ok := findPlainText(t, part.Header, part); ok {
+				return text, true
+			}
+		}
+	}
+	if mediaType != "text/plain" {
+		return "", false
+	}
+
+	if strings.EqualFold(header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b), true
+}
+
+func TestPostForgotMailsResetLink(t *testing.T) {
+	port, received := smtpServer(t)
+	app := testHandlers.App
+	oldMail, oldServer, oldKey := app.Mail, app.Server, app.EncryptionKey
+	t.Cleanup(func() {
+		app.Mail, app.Server, app.EncryptionKey = oldMail, oldServer, oldKey
+		testHandlers.QueueMail = nil
+	})
+	app.Mail = mailer.Mail{
+		Host:         "127.0.0.1",
+		Port:         port,
+		Encryption:   "none",
+		TemplatesDir: "../mails",
+		FromAddress:  "app@example.com",
+		FromName:     "My App",
+	}
+	queued := make(chan mailer.Message, 1)
+	testHandlers.QueueMail = func(msg mailer.Message) { queued <- msg }
+	app.Server.URL = "http://app.example.com"
+	app.EncryptionKey = "0123456789abcdef0123456789abcdef"
+
+	userID, err := testHandlers.Models.Users.Insert(data.User{
+		FirstName: "Forgetful",
+		LastName:  "User",
+		Email:     "forgetful@example.com",
+		Password:  "password",
+		Active:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = testHandlers.Models.Users.Delete(userID)
+	})
+
+	forgot := func(email string) {
+		form := url.Values{"email": {email}}
+		req := httptest.NewRequest(http.MethodPost, "/users/forgot-password", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := serve(testHandlers.PostForgot, req)
+		if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/users/login" {
+			t.Fatalf("expected a redirect to the login page, got %d %s", rr.Code, rr.Header().Get("Location"))
+		}
+	}
+
+	forgot("nobody@example.com")
+	if len(queued) != 0 {
+		t.Fatal("expected no mail for an unknown address")
+	}
+
+	forgot("forgetful@example.com")
+	var job mailer.Message
+	select {
+	case job = <-queued:
+	default:
+		t.Fatal("expected a mail to be queued")
+	}
+	// what the mail listener does with the job
+	if err := app.Mail.Send(job); err != nil {
+		t.Fatal(err)
+	}
+
+	var msg smtpMessage
+	select {
+	case msg = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the SMTP server to receive the mail")
+	}
+	if msg.From != "app@example.com" || len(msg.To) != 1 || msg.To[0] != "forgetful@example.com" {
+		t.Errorf("expected a mail from app@example.com to forgetful@example.com, got %s to %v", msg.From, msg.To)
+	}
+
+	link := regexp.MustCompile(`http://app\.example\.com/\S+`).FindString(plainText(t, msg.Data))
+	if link == "" {
+		t.Fatal("expected the mail to contain a reset link")
+	}
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/users/reset-password" {
+		t.Errorf("expected a link to /users/reset-password, got %s", u.Path)
+	}
+	signer := urlsigner.Signer{Secret: []byte(app.EncryptionKey)}
+	if err := signer.Verify(u.RequestURI()); err != nil {
+		t.Errorf("expected the link to be signed, got %v", err)
+	}
+	reset, err := testHandlers.Models.PasswordResets.Validate(u.Query().Get("token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reset.UserID != userID {
+		t.Errorf("expected the link to reset the password of user %d, got %d", userID, reset.UserID)
+	}
+}
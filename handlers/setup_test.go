@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"myapp/data"
+	"myapp/database"
+	"myapp/database/dbtest"
+	"myapp/encryption"
+	"myapp/i18n"
+	"myapp/validation"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/alexedwards/scs/v2"
+	"github.com/lozhkindm/celeritas"
+	"github.com/lozhkindm/celeritas/render"
+)
+
+var (
+	testHandlers *Handlers
+	// testDB is the database behind testHandlers.Models.
+	testDB *database.Database
+)
+
+func TestMain(m *testing.M) {
+	views := jet.NewSet(jet.NewOSFileSystemLoader("../views"), jet.InDevelopmentMode())
+	validation.AddJetFuncs(views)
+	session := scs.New()
+	lang, err := i18n.Load("../lang", "en")
+	if err != nil {
+		log.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "handlers-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	d, err := dbtest.Open("..", dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+	testDB = d
+	enc, err := encryption.New([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	app := &celeritas.Celeritas{
+		InfoLog:  log.New(io.Discard, "", 0),
+		ErrorLog: log.New(os.Stderr, "ERROR\t", log.Lshortfile),
+		RootPath: "..",
+		JetViews: views,
+		Session:  session,
+		Render: &render.Render{
+			Renderer: "jet",
+			RootPath: "..",
+			JetViews: views,
+			Session:  session,
+		},
+	}
+	testHandlers = &Handlers{App: app, Models: data.New(d), Encrypter: enc, Lang: lang}
+
+	code := m.Run()
+	_ = d.Primary.Close()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// serve runs h behind the session middleware the real router uses.
+func serve(h http.HandlerFunc, r *http.Request) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	testHandlers.App.Session.LoadAndSave(h).ServeHTTP(rr, r)
+	return rr
+}
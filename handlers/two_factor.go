@@ -0,0 +1,335 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"myapp/data"
+	"myapp/totp"
+)
+
+const (
+	// twoFactorTimeout is how long a user has to enter a code after giving
+	// the right password.
+	twoFactorTimeout     = 5 * time.Minute
+	maxTwoFactorAttempts = 5
+	// twoFactorLockout is how long the API refuses codes for a user after
+	// maxTwoFactorAttempts wrong ones in a row.
+	twoFactorLockout = 15 * time.Minute
+)
+
+// startTwoFactor remembers a user who gave the right password, but is not
+// logged in until they enter a code at /users/two-factor.
+func (h *Handlers) startTwoFactor(w http.ResponseWriter, r *http.Request, userID int, remember bool) {
+	if err := h.sessionRenew(r.Context()); err != nil {
+		h.App.ErrorLog.Println("error renewing session:", err)
+		h.App.InternalError(w)
+		return
+	}
+	h.sessionPut(r.Context(), "two_factor_user_id", userID)
+	h.sessionPut(r.Context(), "two_factor_remember", remember)
+	h.sessionPut(r.Context(), "two_factor_started", h.now().Unix())
+	h.sessionPut(r.Context(), "two_factor_attempts", 0)
+
+	http.Redirect(w, r, "/users/two-factor", http.StatusSeeOther)
+}
+
+// pendingTwoFactor returns the user waiting to enter a code, or 0 when there
+// is none or they took too long.
+func (h *Handlers) pendingTwoFactor(r *http.Request) int {
+	userID := h.App.Session.GetInt(r.Context(), "two_factor_user_id")
+	if userID == 0 {
+		return 0
+	}
+	started := time.Unix(h.App.Session.GetInt64(r.Context(), "two_factor_started"), 0)
+	if h.now().Sub(started) > twoFactorTimeout {
+		h.clearTwoFactor(r)
+		return 0
+	}
+	return userID
+}
+
+func (h *Handlers) clearTwoFactor(r *http.Request) {
+	h.sessionRemove(r.Context(), "two_factor_user_id")
+	h.sessionRemove(r.Context(), "two_factor_remember")
+	h.sessionRemove(r.Context(), "two_factor_started")
+	h.sessionRemove(r.Context(), "two_factor_attempts")
+}
+
+func (h *Handlers) TwoFactor(w http.ResponseWriter, r *http.Request) {
+	defer h.App.LoadTime(time.Now())
+	if h.pendingTwoFactor(r) == 0 {
+		http.Redirect(w, r, "/users/login", http.StatusSeeOther)
+		return
+	}
+	h.renderForm(w, r, "two-factor", nil)
+}
+
+// PostTwoFactor finishes a login with a code from the authenticator app or a
+// recovery code. After maxTwoFactorAttempts wrong codes the password has to
+// be entered again.
+func (h *Handlers) PostTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.App.BadRequest(w)
+		return
+	}
+
+	userID := h.pendingTwoFactor(r)
+	if userID == 0 {
+		h.flashError(r, "auth.login_expired")
+		http.Redirect(w, r, "/users/login", http.StatusSeeOther)
+		return
+	}
+
+	v := h.validator(r)
+	v.Required(r, "code")
+	if !v.IsValid() {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		h.renderForm(w, r, "two-factor", v)
+		return
+	}
+
+	ok, err := h.checkTwoFactor(userID, r.Form.Get("code"))
+	if err != nil {
+		h.App.ErrorLog.Println("error checking two-factor code:", err)
+		h.App.InternalError(w)
+		return
+	}
+	if !ok {
+		attempts := h.App.Session.GetInt(r.Context(), "two_factor_attempts") + 1
+		if attempts >= maxTwoFactorAttempts {
+			h.clearTwoFactor(r)
+			h.flashError(r, "auth.too_many_codes")
+			http.Redirect(w, r, "/users/login", http.StatusSeeOther)
+			return
+		}
+		h.sessionPut(r.Context(), "two_factor_attempts", attempts)
+		h.flashError(r, "auth.invalid_code")
+		http.Redirect(w, r, "/users/two-factor", http.StatusSeeOther)
+		return
+	}
+
+	remember := h.App.Session.GetBool(r.Context(), "two_factor_remember")
+	h.clearTwoFactor(r)
+	h.logIn(w, r, userID, remember)
+}
+
+// TwoFactorSetup shows the QR code for the secret being enrolled, or, once 2FA
+// is enabled, the form to turn it off.
+func (h *Handlers) TwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	defer h.App.LoadTime(time.Now())
+	userID := h.App.Session.GetInt(r.Context(), "userID")
+	td := h.templateData(r)
+
+	tf, err := h.Models.TwoFactors.GetForUser(userID)
+	if err != nil {
+		h.App.ErrorLog.Println("error getting two-factor:", err)
+		h.App.InternalError(w)
+		return
+	}
+
+	if tf != nil && tf.Enabled != 0 {
+		remaining, err := h.Models.RecoveryCodes.Remaining(userID)
+		if err != nil {
+			h.App.ErrorLog.Println("error counting recovery codes:", err)
+			h.App.InternalError(w)
+			return
+		}
+		td.IntMap = map[string]int{"enabled": 1, "remaining": remaining}
+	} else {
+		user, err := h.Models.Users.Get(userID)
+		if err != nil {
+			h.App.ErrorLog.Println("error getting user:", err)
+			h.App.InternalError(w)
+			return
+		}
+		secret, err := h.enrolmentSecret(userID, tf)
+		if err != nil {
+			h.App.ErrorLog.Println("error starting two-factor enrolment:", err)
+			h.App.InternalError(w)
+			return
+		}
+		otp, err := h.newTOTP(secret)
+		if err != nil {
+			h.App.ErrorLog.Println("error reading secret:", err)
+			h.App.InternalError(w)
+			return
+		}
+		td.StringMap = map[string]string{
+			"secret": secret,
+			"uri":    otp.URI(h.App.AppName, user.Email),
+		}
+	}
+
+	if err := h.render(w, r, "two-factor-setup", nil, td); err != nil {
+		h.App.ErrorLog.Println("error rendering:", err)
+	}
+}
+
+// PostTwoFactorSetup enables 2FA once the user proves their app has the
+// secret, and shows the recovery codes. They are not shown again. Devices
+// remembered before are forgotten, since their cookies never asked for a
+// code.
+func (h *Handlers) PostTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.App.BadRequest(w)
+		return
+	}
+	userID := h.App.Session.GetInt(r.Context(), "userID")
+
+	tf, err := h.Models.TwoFactors.GetForUser(userID)
+	if err != nil {
+		h.App.ErrorLog.Println("error getting two-factor:", err)
+		h.App.InternalError(w)
+		return
+	}
+	if tf == nil || tf.Enabled != 0 {
+		http.Redirect(w, r, "/users/two-factor/setup", http.StatusSeeOther)
+		return
+	}
+
+	otp, err := h.totpFor(tf.Secret)
+	if err != nil {
+		h.App.ErrorLog.Println("error reading secret:", err)
+		h.App.InternalError(w)
+		return
+	}
+	step, ok := otp.Validate(r.Form.Get("code"))
+	if !ok {
+		h.flashError(r, "auth.invalid_code")
+		http.Redirect(w, r, "/users/two-factor/setup", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.Models.TwoFactors.Enable(userID, step); err != nil {
+		h.App.ErrorLog.Println("error enabling two-factor:", err)
+		h.App.InternalError(w)
+		return
+	}
+	if err := h.Models.RememberTokens.DeleteForUser(userID); err != nil {
+		h.App.ErrorLog.Println("error deleting remember tokens:", err)
+		h.App.InternalError(w)
+		return
+	}
+	h.sessionRemove(r.Context(), "remember_token")
+	codes, err := h.Models.RecoveryCodes.Generate(userID)
+	if err != nil {
+		h.App.ErrorLog.Println("error generating recovery codes:", err)
+		h.App.InternalError(w)
+		return
+	}
+
+	td := h.templateData(r)
+	td.Flash = h.translator(r).T("auth.two_factor_enabled")
+	td.Data = map[string]interface{}{"codes": codes}
+	if err := h.render(w, r, "recovery-codes", nil, td); err != nil {
+		h.App.ErrorLog.Println("error rendering:", err)
+	}
+}
+
+// PostTwoFactorDisable turns 2FA off, which needs a current code.
+func (h *Handlers) PostTwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.App.BadRequest(w)
+		return
+	}
+	userID := h.App.Session.GetInt(r.Context(), "userID")
+
+	ok, err := h.checkTwoFactor(userID, r.Form.Get("code"))
+	if err != nil {
+		h.App.ErrorLog.Println("error checking two-factor code:", err)
+		h.App.InternalError(w)
+		return
+	}
+	if !ok {
+		h.flashError(r, "auth.invalid_code")
+		http.Redirect(w, r, "/users/two-factor/setup", http.StatusSeeOther)
+		return
+	}
+
+	if err := h.Models.TwoFactors.Disable(userID); err != nil {
+		h.App.ErrorLog.Println("error disabling two-factor:", err)
+		h.App.InternalError(w)
+		return
+	}
+	h.flash(r, "auth.two_factor_disabled")
+	http.Redirect(w, r, "/users/two-factor/setup", http.StatusSeeOther)
+}
+
+// checkTwoFactor reports whether code is a valid, unused code for userID:
+// either the current one from the authenticator app or a recovery code.
+// Either kind works only once.
+func (h *Handlers) checkTwoFactor(userID int, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return false, nil
+	}
+
+	tf, err := h.Models.TwoFactors.GetForUser(userID)
+	if err != nil || tf == nil || tf.Enabled == 0 {
+		return false, err
+	}
+
+	if len(strings.ReplaceAll(code, " ", "")) != totp.DefaultDigits {
+		return h.Models.RecoveryCodes.Use(userID, code)
+	}
+
+	otp, err := h.totpFor(tf.Secret)
+	if err != nil {
+		return false, err
+	}
+	step, ok := otp.Validate(code)
+	if !ok {
+		return false, nil
+	}
+	return h.Models.TwoFactors.UseStep(userID, step)
+}
+
+// enrolmentSecret returns the secret of an enrolment that was started but not
+// confirmed, so reloading the setup page keeps the QR code already scanned,
+// or starts a new enrolment.
+func (h *Handlers) enrolmentSecret(userID int, tf *data.TwoFactor) (string, error) {
+	if tf != nil {
+		return h.decrypt(tf.Secret)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+	encrypted, err := h.encrypt(secret)
+	if err != nil {
+		return "", err
+	}
+	if err := h.Models.TwoFactors.Begin(userID, encrypted); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// totpFor decrypts a stored secret.
+func (h *Handlers) totpFor(encrypted string) (*totp.TOTP, error) {
+	secret, err := h.decrypt(encrypted)
+	if err != nil {
+		return nil, err
+	}
+	return h.newTOTP(secret)
+}
+
+// newTOTP returns a TOTP for secret that reads the time from h.Now.
+func (h *Handlers) newTOTP(secret string) (*totp.TOTP, error) {
+	otp, err := totp.New(secret)
+	if err != nil {
+		return nil, err
+	}
+	otp.Now = h.now
+	return otp, nil
+}
+
+func (h *Handlers) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"myapp/data"
+	"myapp/totp"
+)
+
+// enrolTwoFactor creates a user with 2FA enabled, the code for enabledAt
+// having been used to confirm it.
+func enrolTwoFactor(t *testing.T, email string, enabledAt time.Time) (int, *totp.TOTP) {
+	t.Helper()
+	userID, err := testHandlers.Models.Users.Insert(data.User{
+		FirstName: "Two",
+		LastName:  "Factor",
+		Email:     email,
+		Password:  "password",
+		Active:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = testHandlers.Models.TwoFactors.Disable(userID)
+		_ = testHandlers.Models.Users.Delete(userID)
+	})
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := testHandlers.encrypt(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testHandlers.Models.TwoFactors.Begin(userID, encrypted); err != nil {
+		t.Fatal(err)
+	}
+	otp, err := totp.New(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testHandlers.Models.TwoFactors.Enable(userID, otp.Step(enabledAt)); err != nil {
+		t.Fatal(err)
+	}
+	return userID, otp
+}
+
+// setClock points testHandlers at a fake clock for the rest of the test.
+func setClock(t *testing.T, now *time.Time) {
+	testHandlers.Now = func() time.Time { return *now }
+	t.Cleanup(func() { testHandlers.Now = nil })
+}
+
+func TestCheckTwoFactorCodes(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	setClock(t, &now)
+	userID, otp := enrolTwoFactor(t, "totp@example.com", now.Add(-time.Hour))
+
+	tests := []struct {
+		name string
+		at   time.Time
+		ok   bool
+	}{
+		{"previous period", now.Add(-totp.DefaultPeriod), true},
+		{"current period", now, true},
+		{"replayed code", now, false},
+		{"earlier code after a later one", now.Add(-totp.DefaultPeriod), false},
+		{"two periods ahead", now.Add(2 * totp.DefaultPeriod), false},
+		{"next period", now.Add(totp.DefaultPeriod), true},
+	}
+	for _, tt := range tests {
+		ok, err := testHandlers.checkTwoFactor(userID, otp.Code(tt.at))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if ok != tt.ok {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.ok, ok)
+		}
+	}
+
+	// the clock moved on, so a code from the app is current again
+	now = now.Add(5 * time.Minute)
+	if ok, err := testHandlers.checkTwoFactor(userID, otp.Code(now)); err != nil || !ok {
+		t.Errorf("expected the code of the new period to be accepted, got %v, %v", ok, err)
+	}
+}
+
+func TestCheckTwoFactorRecoveryCodes(t *testing.T) {
+	userID, _ := enrolTwoFactor(t, "recovery@example.com", time.Now())
+
+	codes, err := testHandlers.Models.RecoveryCodes.Generate(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := testHandlers.checkTwoFactor(userID, codes[0]); err != nil || !ok {
+		t.Fatalf("expected a recovery code to be accepted, got %v, %v", ok, err)
+	}
+	if ok, _ := testHandlers.checkTwoFactor(userID, codes[0]); ok {
+		t.Error("expected a recovery code to work only once")
+	}
+	typed := strings.ToLower(strings.ReplaceAll(codes[1], "-", ""))
+	if ok, err := testHandlers.checkTwoFactor(userID, typed); err != nil || !ok {
+		t.Errorf("expected a recovery code typed without dashes to be accepted, got %v, %v", ok, err)
+	}
+	if ok, _ := testHandlers.checkTwoFactor(userID, "AAAA-BBBB-CCCC-DDDD"); ok {
+		t.Error("expected an unknown recovery code to be refused")
+	}
+
+	remaining, err := testHandlers.Models.RecoveryCodes.Remaining(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if remaining != len(codes)-2 {
+		t.Errorf("expected %d recovery codes left, got %d", len(codes)-2, remaining)
+	}
+
+	if err := testHandlers.Models.TwoFactors.Disable(userID); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := testHandlers.checkTwoFactor(userID, codes[2]); ok {
+		t.Error("expected recovery codes to stop working once 2FA is disabled")
+	}
+}
+
+func TestPendingTwoFactorExpires(t *testing.T) {
+	now := time.Now()
+	setClock(t, &now)
+
+	// the first request starts the login, the second one looks at it later
+	var pending int
+	start := func(_ http.ResponseWriter, r *http.Request) {
+		testHandlers.sessionPut(r.Context(), "two_factor_user_id", 7)
+		testHandlers.sessionPut(r.Context(), "two_factor_started", testHandlers.now().Unix())
+	}
+	check := func(_ http.ResponseWriter, r *http.Request) {
+		pending = testHandlers.pendingTwoFactor(r)
+	}
+
+	rr := serve(start, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rr.Result().Cookies()
+	later := func(d time.Duration) int {
+		now = now.Add(d)
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		serve(check, req)
+		return pending
+	}
+
+	if got := later(twoFactorTimeout - time.Minute); got != 7 {
+		t.Errorf("expected the login to be pending, got user %d", got)
+	}
+	if got := later(2 * time.Minute); got != 0 {
+		t.Errorf("expected the login to expire after %s, got user %d", twoFactorTimeout, got)
+	}
+}
+
+func TestEnablingTwoFactorForgetsRememberedDevices(t *testing.T) {
+	now := time.Now()
+	setClock(t, &now)
+	userID, err := testHandlers.Models.Users.Insert(data.User{
+		FirstName: "Remembered",
+		LastName:  "User",
+		Email:     "remembered@example.com",
+		Password:  "password",
+		Active:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = testHandlers.Models.TwoFactors.Disable(userID)
+		_ = testHandlers.Models.RememberTokens.DeleteForUser(userID)
+		_ = testHandlers.Models.Users.Delete(userID)
+	})
+	if err := testHandlers.Models.RememberTokens.InsertToken(userID, "remembered-device"); err != nil {
+		t.Fatal(err)
+	}
+
+	// log in and start enrolling
+	rr := serve(func(w http.ResponseWriter, r *http.Request) {
+		testHandlers.sessionPut(r.Context(), "userID", userID)
+	}, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rr.Result().Cookies()
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := testHandlers.encrypt(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testHandlers.Models.TwoFactors.Begin(userID, encrypted); err != nil {
+		t.Fatal(err)
+	}
+	otp, err := totp.New(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	form := url.Values{"code": {otp.Code(now)}}
+	req := httptest.NewRequest(http.MethodPost, "/users/two-factor/setup", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	rr = serve(testHandlers.PostTwoFactorSetup, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected the recovery codes to be shown, got %d", rr.Code)
+	}
+
+	if enabled, err := testHandlers.Models.TwoFactors.EnabledFor(userID); err != nil || !enabled {
+		t.Fatalf("expected 2FA to be enabled, got %v, %v", enabled, err)
+	}
+	if _, err := testHandlers.Models.RememberTokens.Validate(userID, "remembered-device"); !errors.Is(err, data.ErrInvalidRememberToken) {
+		t.Errorf("expected the remember token to be revoked, got %v", err)
+	}
+}
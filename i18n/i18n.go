@@ -0,0 +1,219 @@
+// Package i18n translates the application's text. Translations live in
+// catalogs in the lang folder, one file per locale named after it, such as
+// lang/en.json or lang/pt-BR.yaml. Nested keys are joined with dots, and a
+// key whose value holds plural forms is picked by count:
+//
+//	{
+//	  "inbox": {
+//	    "title": "Hello {name}",
+//	    "messages": {"one": "{count} message", "other": "{count} messages"}
+//	  }
+//	}
+//
+// Plural forms are named as in CLDR: zero, one, two, few, many and other.
+package i18n
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoLocale is returned by Load when the default locale has no catalog
+// while other locales do.
+var ErrNoLocale = errors.New("i18n: no catalog for the default locale")
+
+var pluralForms = map[string]bool{"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true}
+
+// Bundle holds the catalogs of every locale.
+type Bundle struct {
+	// Default is used when no better locale is known, and for keys missing
+	// from a locale's catalog.
+	Default string
+
+	messages map[string]map[string]string            // locale, key
+	plurals  map[string]map[string]map[string]string // locale, key, form
+	lists    map[string]map[string][]string          // locale, key
+}
+
+// New returns an empty Bundle, which translates every key to itself.
+func New(defaultLocale string) *Bundle {
+	return &Bundle{
+		Default:  Normalize(defaultLocale),
+		messages: map[string]map[string]string{},
+		plurals:  map[string]map[string]map[string]string{},
+		lists:    map[string]map[string][]string{},
+	}
+}
+
+// Load reads every .json, .yaml and .yml catalog in dir. A missing dir gives
+// an empty Bundle.
+func Load(dir, defaultLocale string) (*Bundle, error) {
+	b := New(defaultLocale)
+
+	files, err := ioutil.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		ext := filepath.Ext(f.Name())
+		if f.IsDir() || (ext != ".json" && ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		src, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var catalog map[string]interface{}
+		if ext == ".json" {
+			err = json.Unmarshal(src, &catalog)
+		} else {
+			err = yaml.Unmarshal(src, &catalog)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", f.Name(), err)
+		}
+		if err := b.Add(strings.TrimSuffix(f.Name(), ext), catalog); err != nil {
+			return nil, fmt.Errorf("i18n: %s: %w", f.Name(), err)
+		}
+	}
+
+	if len(b.messages) > 0 && !b.Has(b.Default) {
+		return nil, fmt.Errorf("%w %s in %s", ErrNoLocale, b.Default, dir)
+	}
+	return b, nil
+}
+
+// Add merges a catalog, as decoded from JSON or YAML, into locale.
+func (b *Bundle) Add(locale string, catalog map[string]interface{}) error {
+	locale = Normalize(locale)
+	if b.messages[locale] == nil {
+		b.messages[locale] = map[string]string{}
+		b.plurals[locale] = map[string]map[string]string{}
+		b.lists[locale] = map[string][]string{}
+	}
+	return b.add(locale, "", catalog)
+}
+
+func (b *Bundle) add(locale, prefix string, catalog map[string]interface{}) error {
+	for k, value := range catalog {
+		key := prefix + k
+		switch value := value.(type) {
+		case string:
+			b.messages[locale][key] = value
+		case map[string]interface{}:
+			if forms, ok := pluralOf(value); ok {
+				b.plurals[locale][key] = forms
+				continue
+			}
+			if err := b.add(locale, key+".", value); err != nil {
+				return err
+			}
+		case []interface{}:
+			list := make([]string, len(value))
+			for i, item := range value {
+				s, ok := item.(string)
+				if !ok {
+					return fmt.Errorf("%s.%d is not a string", key, i)
+				}
+				list[i] = s
+			}
+			b.lists[locale][key] = list
+		default:
+			return fmt.Errorf("%s is not a string, a list or an object", key)
+		}
+	}
+	return nil
+}
+
+// pluralOf returns the forms of a value made only of plural forms with
+// string values.
+func pluralOf(value map[string]interface{}) (map[string]string, bool) {
+	if _, ok := value["other"]; !ok {
+		return nil, false
+	}
+	forms := make(map[string]string, len(value))
+	for form, v := range value {
+		s, ok := v.(string)
+		if !ok || !pluralForms[form] {
+			return nil, false
+		}
+		forms[form] = s
+	}
+	return forms, true
+}
+
+// Has reports whether locale has a catalog.
+func (b *Bundle) Has(locale string) bool {
+	_, ok := b.messages[Normalize(locale)]
+	return ok
+}
+
+// Locales returns the locales with a catalog, sorted.
+func (b *Bundle) Locales() []string {
+	locales := make([]string, 0, len(b.messages))
+	for l := range b.messages {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Match returns the first of the wanted locales the bundle can serve, using
+// the language alone when a region has no catalog, so de-AT is served by de.
+// It returns "" when none can be served.
+func (b *Bundle) Match(wanted ...string) string {
+	for _, w := range wanted {
+		for _, l := range fallbacks(Normalize(w)) {
+			if b.Has(l) {
+				return l
+			}
+		}
+	}
+	return ""
+}
+
+// Messages returns the plain messages of locale whose keys start with
+// prefix, with the prefix removed, such as the validation messages.
+func (b *Bundle) Messages(locale, prefix string) map[string]string {
+	found := map[string]string{}
+	for k, m := range b.messages[Normalize(locale)] {
+		if strings.HasPrefix(k, prefix) {
+			found[strings.TrimPrefix(k, prefix)] = m
+		}
+	}
+	return found
+}
+
+// Normalize writes a locale the way catalogs are keyed: the language in
+// lower case and the region in upper case, as pt-BR.
+func Normalize(locale string) string {
+	parts := strings.Split(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"), "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// fallbacks returns locale and then its language alone.
+func fallbacks(locale string) []string {
+	if i := strings.Index(locale, "-"); i > 0 {
+		return []string{locale, locale[:i]}
+	}
+	return []string{locale}
+}
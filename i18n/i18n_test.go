@@ -0,0 +1,190 @@
+package i18n
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testBundle loads English, German and Russian catalogs written to a
+// temporary lang folder.
+func testBundle(t *testing.T) *Bundle {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"en.json": `{
+			"greeting": "Hello {name}, you have {count} new {thing}",
+			"inbox": {
+				"messages": {"zero": "No messages", "one": "{count} message", "other": "{count} messages"}
+			},
+			"only_english": "Only in English"
+		}`,
+		"de.yaml": `
+greeting: "Hallo {name}"
+inbox:
+  messages:
+    one: "{count} Nachricht"
+    other: "{count} Nachrichten"
+format:
+  long: "2. January 2006"
+date:
+  months: [Januar, Februar, März, April, Mai, Juni, Juli, August, September, Oktober, November, Dezember]
+  days_short: [So, Mo, Di, Mi, Do, Fr, Sa]
+`,
+		"ru.yml": `
+inbox:
+  messages:
+    one: "{count} сообщение"
+    few: "{count} сообщения"
+    many: "{count} сообщений"
+    other: "{count} сообщения"
+`,
+		"notes.txt": "not a catalog",
+	}
+	for name, src := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, err := Load(dir, "en")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestLoad(t *testing.T) {
+	b := testBundle(t)
+	locales := b.Locales()
+	if len(locales) != 3 || locales[0] != "de" || locales[1] != "en" || locales[2] != "ru" {
+		t.Errorf("expected the locales de, en and ru, got %v", locales)
+	}
+
+	if _, err := Load(t.TempDir(), "en"); err != nil {
+		t.Errorf("expected an empty folder to give an empty bundle, got %v", err)
+	}
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "de.json"), []byte(`{"a": "b"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(dir, "en"); !errors.Is(err, ErrNoLocale) {
+		t.Errorf("expected ErrNoLocale without an en catalog, got %v", err)
+	}
+}
+
+func TestPlurals(t *testing.T) {
+	b := testBundle(t)
+	tests := []struct {
+		locale string
+		count  interface{}
+		want   string
+	}{
+		{"en", 0, "No messages"},
+		{"en", 1, "1 message"},
+		{"en", 2, "2 messages"},
+		{"en", int64(1500), "1,500 messages"},
+		{"de", 1, "1 Nachricht"},
+		{"de", 0, "0 Nachrichten"},
+		{"de", 1500, "1.500 Nachrichten"},
+		{"ru", 1, "1 сообщение"},
+		{"ru", 3, "3 сообщения"},
+		{"ru", 5, "5 сообщений"},
+		{"ru", 21, "21 сообщение"},
+		{"ru", 22, "22 сообщения"},
+	}
+	for _, tt := range tests {
+		got := b.Translator(tt.locale).T("inbox.messages", "count", tt.count)
+		if got != tt.want {
+			t.Errorf("%s, %v: expected %q, got %q", tt.locale, tt.count, tt.want, got)
+		}
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	b := testBundle(t)
+	en := b.Translator("en")
+
+	got := en.T("greeting", "name", "Ada", "count", 2000, "thing", "letters")
+	if want := "Hello Ada, you have 2,000 new letters"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if got := en.T("greeting", "name", "Ada"); got != "Hello Ada, you have {count} new {thing}" {
+		t.Errorf("expected the missing placeholders to be kept, got %q", got)
+	}
+	if got := en.T("greeting", "name"); got != "Hello {name}, you have {count} new {thing}" {
+		t.Errorf("expected a name without a value to be ignored, got %q", got)
+	}
+
+	de := b.Translator("de-AT")
+	if de.Locale != "de" {
+		t.Errorf("expected de-AT to be served by de, got %s", de.Locale)
+	}
+	if got := de.T("greeting", "name", "Ada"); got != "Hallo Ada" {
+		t.Errorf("expected the German greeting, got %q", got)
+	}
+	if got := de.T("only_english"); got != "Only in English" {
+		t.Errorf("expected a key missing in German to come from English, got %q", got)
+	}
+	if got := de.T("nowhere"); got != "nowhere" {
+		t.Errorf("expected an unknown key to be returned as it is, got %q", got)
+	}
+	if got := b.Translator("fr").Locale; got != "en" {
+		t.Errorf("expected an unknown locale to get the default, got %s", got)
+	}
+}
+
+func TestNumber(t *testing.T) {
+	b := testBundle(t)
+	tests := []struct {
+		locale   string
+		n        interface{}
+		decimals int
+		want     string
+	}{
+		{"en", 1234.5, 2, "1,234.50"},
+		{"en", 1234567, 0, "1,234,567"},
+		{"en", 0.125, 1, "0.1"},
+		{"de", 1234.5, 2, "1.234,50"},
+		{"de", uint8(7), 0, "7"},
+		{"en", "n/a", 2, "n/a"},
+	}
+	for _, tt := range tests {
+		if got := b.Translator(tt.locale).Number(tt.n, tt.decimals); got != tt.want {
+			t.Errorf("%s, %v: expected %q, got %q", tt.locale, tt.n, tt.want, got)
+		}
+	}
+}
+
+func TestDate(t *testing.T) {
+	b := testBundle(t)
+	tm := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	tests := []struct {
+		locale, style, want string
+	}{
+		{"en", "date", "2024-03-05"},
+		{"en", "time", "14:30"},
+		{"en", "datetime", "2024-03-05 14:30"},
+		{"en", "long", "March 5, 2024"},
+		{"en", "Mon, 2 Jan", "Tue, 5 Mar"},
+		{"de", "long", "5. März 2024"},
+		{"de", "Mon 02.01.", "Di 05.03."},
+		{"ru", "long", "March 5, 2024"},
+	}
+	for _, tt := range tests {
+		if got := b.Translator(tt.locale).Date(tm, tt.style); got != tt.want {
+			t.Errorf("%s, %s: expected %q, got %q", tt.locale, tt.style, tt.want, got)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := ParseAcceptLanguage("fr;q=0, de-AT;q=0.8, *;q=0.5, en-GB")
+	if len(got) != 2 || got[0] != "en-GB" || got[1] != "de-AT" {
+		t.Errorf("expected en-GB and de-AT, got %v", got)
+	}
+	if got := ParseAcceptLanguage("not a header;;"); len(got) != 0 {
+		t.Errorf("expected nothing from a malformed header, got %v", got)
+	}
+}
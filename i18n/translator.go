@@ -0,0 +1,242 @@
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"golang.org/x/text/feature/plural"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/number"
+)
+
+// Translator translates into one locale.
+type Translator struct {
+	Locale string
+
+	bundle  *Bundle
+	tag     language.Tag
+	printer *message.Printer
+}
+
+// Translator returns a Translator for locale, or for the default locale when
+// locale is not served.
+func (b *Bundle) Translator(locale string) *Translator {
+	if l := b.Match(locale); l != "" {
+		locale = l
+	} else {
+		locale = b.Default
+	}
+	tag := language.Make(locale)
+	return &Translator{Locale: locale, bundle: b, tag: tag, printer: message.NewPrinter(tag)}
+}
+
+// T translates key. args are pairs of a placeholder name and its value, as
+// in T("inbox.title", "name", user.FirstName), which fills in {name}. When a
+// count is given it picks the plural form. A key with no translation in the
+// locale or the default locale is returned as it is.
+func (t *Translator) T(key string, args ...interface{}) string {
+	values := make(map[string]interface{}, len(args)/2)
+	for i := 0; i+1 < len(args); i += 2 {
+		values[fmt.Sprint(args[i])] = args[i+1]
+	}
+
+	s, ok := t.message(key, values)
+	if !ok {
+		return key
+	}
+	if len(values) == 0 {
+		return s
+	}
+
+	pairs := make([]string, 0, len(values)*2)
+	for name, v := range values {
+		pairs = append(pairs, "{"+name+"}", t.format(v))
+	}
+	return strings.NewReplacer(pairs...).Replace(s)
+}
+
+func (t *Translator) message(key string, values map[string]interface{}) (string, bool) {
+	for _, l := range append(fallbacks(t.Locale), t.bundle.Default) {
+		if forms, ok := t.bundle.plurals[l][key]; ok {
+			count, _ := toFloat(values["count"])
+			// English has no zero form of its own, but catalogs often
+			// want "No messages"
+			if s, ok := forms["zero"]; ok && count == 0 {
+				return s, true
+			}
+			if s, ok := forms[pluralForm(l, count)]; ok {
+				return s, true
+			}
+			return forms["other"], true
+		}
+		if s, ok := t.bundle.messages[l][key]; ok {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// pluralForm returns the CLDR plural form of a whole count in locale l.
+func pluralForm(l string, count float64) string {
+	switch plural.Cardinal.MatchPlural(language.Make(l), int(count), 0, 0, 0, 0) {
+	case plural.Zero:
+		return "zero"
+	case plural.One:
+		return "one"
+	case plural.Two:
+		return "two"
+	case plural.Few:
+		return "few"
+	case plural.Many:
+		return "many"
+	default:
+		return "other"
+	}
+}
+
+// Number formats n, any integer or float, with the grouping and decimal
+// separator of the locale and exactly decimals digits after the point.
+func (t *Translator) Number(n interface{}, decimals int) string {
+	f, ok := toFloat(n)
+	if !ok {
+		return fmt.Sprint(n)
+	}
+	return t.printer.Sprint(number.Decimal(f, number.MinFractionDigits(decimals), number.MaxFractionDigits(decimals)))
+}
+
+// Default date layouts, used unless the catalog sets format.<style>.
+var dateLayouts = map[string]string{
+	"date":     "2006-01-02",
+	"time":     "15:04",
+	"datetime": "2006-01-02 15:04",
+	"long":     "January 2, 2006",
+}
+
+var (
+	englishMonths = []string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+	englishDays   = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+)
+
+// Date formats tm in a style: date, time, datetime or long, or a Go layout.
+// The catalog sets the layout of a style as format.<style>, and the names of
+// months and days as the lists date.months, date.months_short, date.days and
+// date.days_short, with days starting on Sunday.
+func (t *Translator) Date(tm time.Time, style string) string {
+	layout, ok := t.message("format."+style, nil)
+	if !ok {
+		if layout, ok = dateLayouts[style]; !ok {
+			layout = style
+		}
+	}
+
+	s := tm.Format(layout)
+	month, day := int(tm.Month())-1, int(tm.Weekday())
+	switch {
+	case strings.Contains(layout, "January"):
+		s = t.replaceName(s, "date.months", englishMonths[month], month)
+	case strings.Contains(layout, "Jan"):
+		s = t.replaceName(s, "date.months_short", englishMonths[month][:3], month)
+	}
+	switch {
+	case strings.Contains(layout, "Monday"):
+		s = t.replaceName(s, "date.days", englishDays[day], day)
+	case strings.Contains(layout, "Mon"):
+		s = t.replaceName(s, "date.days_short", englishDays[day][:3], day)
+	}
+	return s
+}
+
+func (t *Translator) replaceName(s, key, english string, i int) string {
+	for _, l := range append(fallbacks(t.Locale), t.bundle.Default) {
+		if names, ok := t.bundle.lists[l][key]; ok && i < len(names) {
+			return strings.Replace(s, english, names[i], 1)
+		}
+	}
+	return s
+}
+
+func (t *Translator) format(v interface{}) string {
+	switch v := v.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return t.Number(v, 0)
+	case time.Time:
+		return t.Date(v, "date")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// FuncMap returns the functions t, date and number for Go templates, as in
+// {{t "inbox.messages" "count" .Count}} or {{date .CreatedAt "long"}}.
+func (t *Translator) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"t":      t.T,
+		"date":   t.Date,
+		"number": t.Number,
+	}
+}
+
+func toFloat(n interface{}) (float64, bool) {
+	switch n := n.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	default:
+		return 0, false
+	}
+}
+
+type contextKey struct{}
+
+// WithTranslator returns a copy of ctx carrying t.
+func WithTranslator(ctx context.Context, t *Translator) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the Translator stored by WithTranslator, or nil.
+func FromContext(ctx context.Context) *Translator {
+	t, _ := ctx.Value(contextKey{}).(*Translator)
+	return t
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header, most
+// wanted first. Locales with a weight of 0, the * wildcard and malformed
+// entries are left out.
+func ParseAcceptLanguage(header string) []string {
+	tags, weights, err := language.ParseAcceptLanguage(header)
+	if err != nil {
+		return nil
+	}
+	locales := make([]string, 0, len(tags))
+	for i, tag := range tags {
+		if weights[i] > 0 && tag != language.Und && tag.String() != "mul" {
+			locales = append(locales, tag.String())
+		}
+	}
+	return locales
+}
@@ -3,10 +3,18 @@ package main
 import (
 	"log"
 	"os"
+	"path/filepath"
 
+	"myapp/cache"
+	"myapp/config"
+	"myapp/cookies"
 	"myapp/data"
+	"myapp/database"
+	"myapp/encryption"
 	"myapp/handlers"
+	"myapp/i18n"
 	"myapp/middlewares"
+	"myapp/validation"
 
 	"github.com/lozhkindm/celeritas"
 )
@@ -17,24 +25,123 @@ func initApplication() *application {
 		log.Fatal(err)
 	}
 
+	cfg, err := config.New(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	oldKeys := make([][]byte, len(cfg.OldKeys))
+	for i, key := range cfg.OldKeys {
+		oldKeys[i] = []byte(key)
+	}
+	enc, err := encryption.New([]byte(cfg.Key), oldKeys...)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	lang, err := i18n.Load(filepath.Join(path, "lang"), cfg.Locale)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, locale := range lang.Locales() {
+		validation.SetMessages(locale, lang.Messages(locale, "validation."))
+	}
+
 	cel := &celeritas.Celeritas{}
+	restoreDBEnv := hideDBEnv()
+	removeDotEnv := guardDotEnv(path)
 	if err := cel.New(path); err != nil {
 		log.Fatal(err)
 	}
+	removeDotEnv()
+	restoreDBEnv()
+
+	db, err := connectDB(cel, cfg)
+	if err != nil {
+		cel.ErrorLog.Fatal(err)
+	}
+
+	// celeritas only creates the redis and badger caches
+	var memoryCache *cache.Memory
+	if cfg.Cache == "memory" {
+		memoryCache = cache.NewMemory(cache.MemoryOptions{
+			MaxEntries:      cfg.MemoryCache.MaxEntries,
+			MaxBytes:        cfg.MemoryCache.MaxBytes,
+			JanitorInterval: cfg.MemoryCache.JanitorInterval,
+		})
+		cel.Cache = memoryCache
+	}
 
 	cel.AppName = "myapp"
 	cel.Debug = true
 
+	jar := &cookies.Jar{
+		Secret:    []byte(cfg.Key),
+		Encrypter: enc,
+		Domain:    cfg.Cookie.Domain,
+		Secure:    cfg.Cookie.Secure,
+	}
+
 	app := &application{
 		App:         cel,
-		Handlers:    &handlers.Handlers{App: cel},
-		Middlewares: &middlewares.Middleware{App: cel},
+		Config:      cfg,
+		DB:          db,
+		Encrypter:   enc,
+		Handlers:    &handlers.Handlers{App: cel, Encrypter: enc, Cookies: jar, Lang: lang},
+		Middlewares: &middlewares.Middleware{App: cel, Cookies: jar, Lang: lang},
+	}
+
+	if memoryCache != nil {
+		app.OnShutdown(memoryCache.Close)
+	}
+	if cel.Cache != nil {
+		if app.Cache, err = cache.NewStore(cel.Cache); err != nil {
+			cel.ErrorLog.Fatal(err)
+		}
+		app.Cache.OnError = func(err error) {
+			cel.ErrorLog.Println("cache error:", err)
+		}
+	}
+
+	app.Handlers.QueueMail = app.queueMail
+	app.setupMail()
+	app.setupViews()
+
+	if db != nil {
+		app.OnShutdown(db.Close)
+		app.DBHealth = database.NewHealth(cel.DB.Pool, cfg.Database.HealthCheckInterval)
+		app.DBHealth.OnError = func(err error) {
+			cel.ErrorLog.Println("database health check failed:", err)
+		}
+		app.DBHealth.Start()
+		app.OnShutdown(app.DBHealth.Stop)
 	}
 
 	app.App.Routes = app.routes()
-	app.Models = data.New(app.App.DB.Pool)
+	app.API = app.apiRoutes()
+	app.Models = data.New(db)
+	data.UseCache(app.Cache)
+	validation.UseDatabase(data.Lookup{})
 	app.Handlers.Models = app.Models
+	app.Handlers.Cache = app.Cache
+	app.Handlers.Providers = app.oauthProviders()
 	app.Middlewares.Models = app.Models
 
 	return app
 }
+
+// guardDotEnv returns a function that removes the empty .env celeritas
+// creates in path when there is none, as when KEY comes from the
+// environment, so that the file does not look like a configuration later.
+func guardDotEnv(path string) func() {
+	file := filepath.Join(path, ".env")
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		return func() {}
+	}
+
+	return func() {
+		if info, err := os.Stat(file); err == nil && info.Size() == 0 {
+			_ = os.Remove(file)
+		}
+	}
+}
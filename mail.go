@@ -0,0 +1,29 @@
+package main
+
+import "github.com/lozhkindm/celeritas/mailer"
+
+// setupMail corrects the sender, which celeritas reads from swapped
+// variables, and logs the outcome of every message sent in the background.
+// Nothing else reads the results, and the mail listener stops once their
+// buffer is full. Every result marks one message from queueMail as sent.
+func (a *application) setupMail() {
+	a.App.Mail.FromAddress = a.Config.Mail.FromAddress
+	a.App.Mail.FromName = a.Config.Mail.FromName
+
+	go func() {
+		for res := range a.App.Mail.Results {
+			if res.Error != nil {
+				a.App.ErrorLog.Println("error sending mail:", res.Error)
+			}
+			a.mailing.Done()
+		}
+	}()
+}
+
+// queueMail hands msg to the mail listener and counts it as unsent until its
+// result comes back. Nothing else may write to the jobs channel, or the
+// count setupMail keeps would go below zero.
+func (a *application) queueMail(msg mailer.Message) {
+	a.mailing.Add(1)
+	a.App.Mail.Jobs <- msg
+}
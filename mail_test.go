@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"testing"
+	"time"
+
+	"myapp/config"
+
+	"github.com/lozhkindm/celeritas"
+	"github.com/lozhkindm/celeritas/mailer"
+)
+
+func TestDrainMailWaitsForQueuedMail(t *testing.T) {
+	a := &application{
+		App: &celeritas.Celeritas{
+			ErrorLog: log.New(io.Discard, "", 0),
+			Mail: mailer.Mail{
+				Jobs:    make(chan mailer.Message, 20),
+				Results: make(chan mailer.Result, 20),
+			},
+		},
+		Config: &config.Config{},
+	}
+	a.setupMail()
+	a.queueMail(mailer.Message{To: "someone@example.com"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := a.drainMail(ctx); err == nil {
+		t.Fatal("expected shutdown to wait for the queued mail")
+	}
+
+	// what the mail listener does with the job
+	<-a.App.Mail.Jobs
+	a.App.Mail.Results <- mailer.Result{Error: errors.New("refused")}
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := a.drainMail(ctx); err != nil {
+		t.Errorf("expected the mail to count as sent once its result is in, got %v", err)
+	}
+}
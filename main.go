@@ -2,20 +2,36 @@ package main
 
 import (
 	"log"
+	"os"
+	"sync"
 	"time"
 
+	"myapp/cache"
+	"myapp/config"
 	"myapp/data"
+	"myapp/database"
+	"myapp/encryption"
 	"myapp/handlers"
 	"myapp/middlewares"
 
+	"github.com/go-chi/chi/v5"
 	"github.com/lozhkindm/celeritas"
 )
 
 type application struct {
 	App         *celeritas.Celeritas
+	Config      *config.Config
+	API         *chi.Mux
 	Handlers    *handlers.Handlers
 	Models      data.Models
 	Middlewares *middlewares.Middleware
+	DB          *database.Database
+	DBHealth    *database.Health
+	Encrypter   *encryption.Encrypter
+	Cache       *cache.Store
+
+	shutdownHooks []func() error
+	mailing       sync.WaitGroup
 }
 
 func main() {
@@ -25,6 +41,7 @@ func main() {
 	}
 	time.Local = loc
 
-	c := initApplication()
-	c.App.ListenAndServe()
+	if err := run(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"myapp/config"
+	"myapp/database"
+)
+
+//go:embed templates
+var templateFS embed.FS
+
+// generated describes the thing being generated, as seen by the templates.
+type generated struct {
+	Name   string // exported Go identifier, e.g. UserProfile
+	Plural string // e.g. UserProfiles
+	Snake  string // e.g. user_profile
+	Table  string // e.g. user_profiles
+	View   string // e.g. user-profile
+	Title  string // e.g. User Profile
+}
+
+func makeCommand(rootPath string, args []string) error {
+	if len(args) == 1 && args[0] == "auth" {
+		return makeAuth(rootPath)
+	}
+	if len(args) < 2 {
+		return errors.New("make needs a kind and a name, e.g. make handler user-profile")
+	}
+
+	kind, name := args[0], args[1]
+	g, err := newGenerated(name)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case "migration":
+		return makeMigration(rootPath, g)
+	case "handler":
+		return makeHandler(rootPath, g)
+	case "model":
+		return makeModel(rootPath, g)
+	case "middleware":
+		return writeFiles(rootPath, g, map[string]string{
+			"middleware.go.tmpl":      "middlewares/" + g.Snake + ".go",
+			"middleware_test.go.tmpl": "middlewares/" + g.Snake + "_test.go",
+		})
+	case "mail":
+		if err := writeTemplate("mail.html.tmpl", filepath.Join(rootPath, "mails", g.View+".html.tmpl"), g); err != nil {
+			return err
+		}
+		return writeTemplate("mail.plain.tmpl", filepath.Join(rootPath, "mails", g.View+".plain.tmpl"), g)
+	default:
+		return fmt.Errorf("unknown make command %q", kind)
+	}
+}
+
+func makeMigration(rootPath string, g generated) error {
+	base := fmt.Sprintf("%d_%s", time.Now().UnixMicro(), g.Snake)
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(rootPath, "migrations", fmt.Sprintf("%s.%s.sql", base, direction))
+		if err := writeFile(path, []byte(fmt.Sprintf("-- %s migration for %s\n", direction, g.Snake))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeAuth copies the migrations for the users, remember tokens and other
+// authentication tables, written for the configured DATABASE_TYPE. Tables
+// that already have a migration are skipped, so it is safe to run again after
+// upgrading the skeleton.
+func makeAuth(rootPath string) error {
+	if err := config.LoadEnv(rootPath); err != nil {
+		return err
+	}
+	dialect, err := migrationDialect(os.Getenv("DATABASE_TYPE"))
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(rootPath, "migrations")
+	base := time.Now().UnixMicro()
+	for i, name := range database.AuthMigrations {
+		existing, err := filepath.Glob(filepath.Join(dir, "*_"+name+".up.sql"))
+		if err != nil {
+			return err
+		}
+		if len(existing) > 0 {
+			continue
+		}
+
+		for _, direction := range []string{"up", "down"} {
+			src, err := templateFS.ReadFile(fmt.Sprintf("templates/migrations/%s/%s.%s.sql", dialect, name, direction))
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(dir, fmt.Sprintf("%d_%s.%s.sql", base+int64(i), name, direction))
+			if err := writeFile(path, src); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func migrationDialect(dbType string) (string, error) {
+	switch dbType {
+	case "postgres", "postgresql":
+		return "postgres", nil
+	case "mysql", "mariadb":
+		return "mysql", nil
+	case "sqlite", "sqlite3":
+		return "sqlite", nil
+	case "":
+		return "", errors.New("DATABASE_TYPE is not set")
+	default:
+		return "", fmt.Errorf("no migrations for DATABASE_TYPE %s", dbType)
+	}
+}
+
+// makeHandler writes the handler, its test and its view, then registers a GET
+// route for it in routes.go.
+func makeHandler(rootPath string, g generated) error {
+	err := writeFiles(rootPath, g, map[string]string{
+		"handler.go.tmpl":      "handlers/" + g.Snake + ".go",
+		"handler_test.go.tmpl": "handlers/" + g.Snake + "_test.go",
+		"view.jet.tmpl":        "views/" + g.View + ".jet",
+	})
+	if err != nil {
+		return err
+	}
+	if err := ensureFile(rootPath, "handlers_setup_test.go.tmpl", "handlers/setup_test.go", g); err != nil {
+		return err
+	}
+
+	route := fmt.Sprintf("a.routeGet(\"/%s\", a.Handlers.%s)", g.View, g.Name)
+	return editGoFile(filepath.Join(rootPath, "routes.go"), func(src string) (string, error) {
+		i := strings.Index(src, "\t// routes\n")
+		if i < 0 {
+			return "", fmt.Errorf("could not find the routes comment in routes.go; add %s yourself", route)
+		}
+		end := strings.Index(src[i:], "\n\n")
+		if end < 0 {
+			return "", fmt.Errorf("could not find the end of the routes in routes.go; add %s yourself", route)
+		}
+		end += i
+		return src[:end] + "\n\t" + route + src[end:], nil
+	})
+}
+
+// makeModel writes the model and its test, then adds it to data.Models and
+// to the value returned by data.New.
+func makeModel(rootPath string, g generated) error {
+	err := writeFiles(rootPath, g, map[string]string{
+		"model.go.tmpl":      "data/" + g.Snake + ".go",
+		"model_test.go.tmpl": "data/" + g.Snake + "_test.go",
+	})
+	if err != nil {
+		return err
+	}
+	if err := ensureFile(rootPath, "data_setup_test.go.tmpl", "data/setup_test.go", g); err != nil {
+		return err
+	}
+
+	return editGoFile(filepath.Join(rootPath, "data", "models.go"), func(src string) (string, error) {
+		src, ok := appendToBraces(src, "type Models struct", fmt.Sprintf("%s %s", g.Plural, g.Name), false)
+		if !ok {
+			return "", fmt.Errorf("could not find the Models struct in data/models.go; add %s yourself", g.Plural)
+		}
+		src, ok = appendToBraces(src, "return Models", fmt.Sprintf("%s: %s{},", g.Plural, g.Name), true)
+		if !ok {
+			return "", fmt.Errorf("could not find the return of data.New in data/models.go; add %s yourself", g.Plural)
+		}
+		return src, nil
+	})
+}
+
+// appendToBraces adds line just before the closing brace that follows prefix,
+// using the first occurrence of prefix, or the last one when last is set.
+func appendToBraces(src, prefix, line string, last bool) (string, bool) {
+	i := strings.Index(src, prefix)
+	if last {
+		i = strings.LastIndex(src, prefix)
+	}
+	if i < 0 {
+		return src, false
+	}
+	open := strings.Index(src[i:], "{")
+	if open < 0 {
+		return src, false
+	}
+
+	depth := 0
+	for end := i + open; end < len(src); end++ {
+		switch src[end] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return strings.TrimRight(src[:end], " \t\n") + "\n" + line + "\n" + src[end:], true
+			}
+		}
+	}
+	return src, false
+}
+
+// editGoFile rewrites an existing Go file with edit and gofmts the result.
+func editGoFile(path string, edit func(string) (string, error)) error {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, err := edit(string(src))
+	if err != nil {
+		return err
+	}
+	formatted, err := format.Source([]byte(out))
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, formatted, 0644); err != nil {
+		return err
+	}
+	fmt.Println("Updated", path)
+	return nil
+}
+
+// writeFiles renders each template into its path, relative to rootPath. Every
+// path is checked first so nothing is written when one of them exists.
+func writeFiles(rootPath string, g generated, files map[string]string) error {
+	for _, path := range files {
+		if _, err := os.Stat(filepath.Join(rootPath, path)); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+	}
+	for name, path := range files {
+		if err := writeTemplate(name, filepath.Join(rootPath, path), g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ensureFile renders a shared file, such as a package's test setup, unless an
+// earlier generator already created it.
+func ensureFile(rootPath, name, path string, g generated) error {
+	if _, err := os.Stat(filepath.Join(rootPath, path)); err == nil {
+		return nil
+	}
+	return writeTemplate(name, filepath.Join(rootPath, path), g)
+}
+
+// writeTemplate renders a template from the templates folder into path. The
+// templates use [[ ]] delimiters so mail templates can keep their own {{ }}.
+func writeTemplate(name, path string, g generated) error {
+	tmpl, err := template.New(name).Delims("[[", "]]").ParseFS(templateFS, "templates/"+name)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, g); err != nil {
+		return err
+	}
+
+	out := buf.Bytes()
+	if filepath.Ext(path) == ".go" {
+		if out, err = format.Source(out); err != nil {
+			return err
+		}
+	}
+	return writeFile(path, out)
+}
+
+// writeFile creates path, refusing to overwrite an existing file.
+func writeFile(path string, content []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, content, 0644); err != nil {
+		return err
+	}
+	fmt.Println("Created", path)
+	return nil
+}
+
+// newGenerated splits name on dashes, underscores, spaces and case changes,
+// so user-profile, user_profile and UserProfile all give the same result.
+func newGenerated(name string) (generated, error) {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	for i, r := range name {
+		switch {
+		case r == '-' || r == '_' || r == ' ':
+			flush()
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if unicode.IsUpper(r) && i > 0 && len(word) > 0 && !unicode.IsUpper(word[len(word)-1]) {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			return generated{}, fmt.Errorf("invalid name %q", name)
+		}
+	}
+	flush()
+
+	if len(words) == 0 || !unicode.IsLetter([]rune(words[0])[0]) {
+		return generated{}, fmt.Errorf("invalid name %q", name)
+	}
+
+	camel := make([]string, len(words))
+	for i, w := range words {
+		camel[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	last := len(camel) - 1
+	pluralName := strings.Join(camel[:last], "") + plural(camel[last])
+
+	snake := strings.Join(words, "_")
+	return generated{
+		Name:   strings.Join(camel, ""),
+		Plural: pluralName,
+		Snake:  snake,
+		Table:  plural(snake),
+		View:   strings.Join(words, "-"),
+		Title:  strings.Join(camel, " "),
+	}, nil
+}
+
+func plural(s string) string {
+	switch {
+	case strings.HasSuffix(s, "y") && !strings.HasSuffix(s, "ay") && !strings.HasSuffix(s, "ey") && !strings.HasSuffix(s, "oy"):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	default:
+		return s + "s"
+	}
+}
@@ -0,0 +1,14 @@
+package middlewares
+
+import "net/http"
+
+// Auth sends visitors who are not logged in to the login page.
+func (m *Middleware) Auth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.App.Session.Exists(r.Context(), "userID") {
+			http.Redirect(w, r, "/users/login", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+	"strings"
+
+	"myapp/i18n"
+)
+
+// localeKey names the session value and cookie that hold the locale a user
+// picked with handlers.SetLocale.
+const localeKey = "locale"
+
+// Locale picks the locale of each request and puts its translator in the
+// request context. It takes the first of:
+//
+//	a locale prefix on the path, as in /de/users/login, stripped before routing
+//	the locale saved in the session
+//	the locale cookie
+//	the Accept-Language header
+//	the default locale
+func (m *Middleware) Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale, path := m.localePrefix(r.URL.Path)
+		if locale != "" {
+			r.URL.Path, r.URL.RawPath = path, ""
+		}
+		if locale == "" {
+			locale = m.Lang.Match(m.App.Session.GetString(r.Context(), localeKey))
+		}
+		if locale == "" {
+			if picked, err := m.Cookies.GetSigned(r, localeKey); err == nil {
+				locale = m.Lang.Match(picked)
+			}
+		}
+		if locale == "" {
+			locale = m.Lang.Match(i18n.ParseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+		}
+
+		t := m.Lang.Translator(locale)
+		w.Header().Set("Content-Language", t.Locale)
+		w.Header().Add("Vary", "Accept-Language")
+		next.ServeHTTP(w, r.WithContext(i18n.WithTranslator(r.Context(), t)))
+	})
+}
+
+// localePrefix splits a served locale off the start of path.
+func (m *Middleware) localePrefix(path string) (string, string) {
+	segment := strings.TrimPrefix(path, "/")
+	rest := "/"
+	if i := strings.Index(segment, "/"); i >= 0 {
+		segment, rest = segment[:i], segment[i:]
+	}
+	if segment == "" || !m.Lang.Has(segment) {
+		return "", path
+	}
+	return i18n.Normalize(segment), rest
+}
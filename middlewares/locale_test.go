@@ -0,0 +1,76 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"myapp/cookies"
+	"myapp/i18n"
+)
+
+func TestLocaleOrder(t *testing.T) {
+	lang := i18n.New("en")
+	for _, locale := range []string{"en", "de", "fr", "pt-BR"} {
+		if err := lang.Add(locale, map[string]interface{}{"hello": locale}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jar := &cookies.Jar{Secret: []byte("0123456789abcdef0123456789abcdef")}
+	m := &Middleware{App: testMiddleware.App, Lang: lang, Cookies: jar}
+
+	// a session in which the user picked pt-BR
+	rr := serve(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.App.Session.Put(r.Context(), localeKey, "pt-BR")
+	}), httptest.NewRequest(http.MethodGet, "/", nil))
+	session := rr.Result().Cookies()
+
+	cookie := httptest.NewRecorder()
+	jar.SetSigned(cookie, localeKey, "fr", time.Hour)
+	picked := cookie.Result().Cookies()
+
+	tests := []struct {
+		name    string
+		path    string
+		cookies [][]*http.Cookie
+		accept  string
+		locale  string
+		rest    string
+	}{
+		{"path prefix first", "/de/users/login", [][]*http.Cookie{session, picked}, "fr", "de", "/users/login"},
+		{"path prefix alone", "/de", nil, "", "de", "/"},
+		{"unknown prefix is a path", "/es/users", nil, "", "en", "/es/users"},
+		{"session before the cookie", "/users", [][]*http.Cookie{session, picked}, "de", "pt-BR", "/users"},
+		{"cookie before the header", "/users", [][]*http.Cookie{picked}, "de", "fr", "/users"},
+		{"accept-language region falls back", "/users", nil, "es, de-AT;q=0.9", "de", "/users"},
+		{"default", "/users", nil, "es", "en", "/users"},
+	}
+	for _, tt := range tests {
+		var locale, path string
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tr := i18n.FromContext(r.Context()); tr != nil {
+				locale = tr.Locale
+			}
+			path = r.URL.Path
+		})
+
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		for _, cs := range tt.cookies {
+			for _, c := range cs {
+				req.AddCookie(c)
+			}
+		}
+		if tt.accept != "" {
+			req.Header.Set("Accept-Language", tt.accept)
+		}
+		rr := serve(m.Locale(next), req)
+
+		if locale != tt.locale || path != tt.rest {
+			t.Errorf("%s: expected %s at %s, got %s at %s", tt.name, tt.locale, tt.rest, locale, path)
+		}
+		if got := rr.Header().Get("Content-Language"); got != tt.locale {
+			t.Errorf("%s: expected Content-Language %s, got %s", tt.name, tt.locale, got)
+		}
+	}
+}
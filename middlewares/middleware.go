@@ -1,12 +1,16 @@
 package middlewares
 
 import (
+	"myapp/cookies"
 	"myapp/data"
+	"myapp/i18n"
 
 	"github.com/lozhkindm/celeritas"
 )
 
 type Middleware struct {
-	App    *celeritas.Celeritas
-	Models data.Models
+	App     *celeritas.Celeritas
+	Models  data.Models
+	Cookies *cookies.Jar
+	Lang    *i18n.Bundle
 }
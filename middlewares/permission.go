@@ -0,0 +1,53 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+
+	"myapp/apierror"
+	"myapp/data"
+)
+
+// RequirePermission lets a request through only when the user has permission,
+// directly or through data.AllPermissions. Visitors who are not logged in are
+// sent to the login page; logged in users without the permission get
+// Forbidden. Behind AuthToken, both answers are JSON.
+func (m *Middleware) RequirePermission(permission string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// API routes have no session, so AuthToken must run first there
+			var userID int
+			apiUser := data.UserFromContext(r.Context())
+			if apiUser != nil {
+				userID = apiUser.ID
+			} else {
+				userID = m.App.Session.GetInt(r.Context(), "userID")
+			}
+			if userID == 0 {
+				http.Redirect(w, r, "/users/login", http.StatusSeeOther)
+				return
+			}
+
+			allowed, err := m.Models.Permissions.UserHas(userID, permission)
+			if err != nil {
+				m.App.ErrorLog.Println("error checking permission:", err)
+				if apiUser != nil {
+					apierror.Write(m.App, w, http.StatusInternalServerError, "internal server error")
+				} else {
+					m.App.InternalError(w)
+				}
+				return
+			}
+
+			if !allowed {
+				if apiUser != nil {
+					apierror.Write(m.App, w, http.StatusForbidden, fmt.Sprintf("missing the %s permission", permission))
+				} else {
+					m.App.Forbidden(w)
+				}
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"myapp/data"
+)
+
+// requirePermission sends a request through RequirePermission("posts.edit"),
+// logged in as userID through the session unless it is 0, or as apiUser
+// through the request context when it is set.
+func requirePermission(userID int, apiUser *data.User) (*httptest.ResponseRecorder, *passed) {
+	next := &passed{}
+	h := testMiddleware.RequirePermission("posts.edit")(next)
+	login := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID != 0 {
+			testMiddleware.App.Session.Put(r.Context(), "userID", userID)
+		}
+		if apiUser != nil {
+			r = r.WithContext(data.WithUser(r.Context(), apiUser))
+		}
+		h.ServeHTTP(w, r)
+	})
+	return serve(login, httptest.NewRequest(http.MethodGet, "/posts/1/edit", nil)), next
+}
+
+func TestRequirePermission(t *testing.T) {
+	editor := insertUser(t, "permission-editor@example.com")
+	reader := insertUser(t, "permission-reader@example.com")
+	roles := testMiddleware.Models.Roles
+	if err := roles.Assign(editor, "permission-editor"); err != nil {
+		t.Fatal(err)
+	}
+	if err := roles.Grant("permission-editor", "posts.edit"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = roles.Revoke("permission-editor", "posts.edit")
+		_ = roles.Unassign(editor, "permission-editor")
+	})
+
+	rr, next := requirePermission(0, nil)
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/users/login" || next.called {
+		t.Errorf("expected a guest to be sent to the login page, got %d to %q", rr.Code, rr.Header().Get("Location"))
+	}
+
+	rr, next = requirePermission(reader, nil)
+	if rr.Code != http.StatusForbidden || next.called {
+		t.Errorf("expected status %d without the permission, got %d", http.StatusForbidden, rr.Code)
+	}
+
+	rr, next = requirePermission(editor, nil)
+	if rr.Code != http.StatusOK || !next.called {
+		t.Errorf("expected a user with the permission to get through, got %d", rr.Code)
+	}
+}
+
+func TestRequirePermissionAnswersAPIInJSON(t *testing.T) {
+	editor := insertUser(t, "permission-api-editor@example.com")
+	reader := insertUser(t, "permission-api-reader@example.com")
+	roles := testMiddleware.Models.Roles
+	if err := roles.Assign(editor, "permission-admin"); err != nil {
+		t.Fatal(err)
+	}
+	if err := roles.Grant("permission-admin", data.AllPermissions); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = roles.Revoke("permission-admin", data.AllPermissions)
+		_ = roles.Unassign(editor, "permission-admin")
+	})
+
+	rr, next := requirePermission(0, &data.User{ID: reader})
+	if rr.Code != http.StatusForbidden || next.called {
+		t.Fatalf("expected status %d without the permission, got %d", http.StatusForbidden, rr.Code)
+	}
+	var res struct {
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil || !res.Error || res.Message != "missing the posts.edit permission" {
+		t.Errorf("expected a JSON error naming the permission, got %+v, %v", res, err)
+	}
+
+	rr, next = requirePermission(0, &data.User{ID: editor})
+	if rr.Code != http.StatusOK || !next.called || next.user == nil || next.user.ID != editor {
+		t.Errorf("expected a user with every permission to get through, got %d", rr.Code)
+	}
+}
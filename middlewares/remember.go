@@ -0,0 +1,77 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"myapp/cookies"
+	"myapp/data"
+)
+
+// CheckRemember logs a visitor back in from a valid remember-me cookie. The
+// token is rotated on every use; a cookie that does not check out is deleted.
+// Enabling 2FA revokes the user's tokens, so a token never skips the code of
+// a user who has it on.
+func (m *Middleware) CheckRemember(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.App.Session.Exists(r.Context(), "userID") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		name := m.App.GetRememberMeCookieName()
+		value, err := m.Cookies.GetEncrypted(r, name)
+		if errors.Is(err, http.ErrNoCookie) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err == nil {
+			err = m.restoreLogin(w, r, value)
+		}
+
+		if err != nil {
+			if errors.Is(err, cookies.ErrInvalid) {
+				m.App.InfoLog.Println("rejected an invalid remember-me cookie")
+			} else if !errors.Is(err, cookies.ErrExpired) && !errors.Is(err, data.ErrInvalidRememberToken) {
+				m.App.ErrorLog.Println("error restoring remembered login:", err)
+			}
+			m.Cookies.Delete(w, name)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *Middleware) restoreLogin(w http.ResponseWriter, r *http.Request, value string) error {
+	parts := strings.SplitN(value, "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return data.ErrInvalidRememberToken
+	}
+	userID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return data.ErrInvalidRememberToken
+	}
+
+	if _, err := m.Models.RememberTokens.Validate(userID, parts[1]); err != nil {
+		return err
+	}
+	user, err := m.Models.Users.Get(userID)
+	if err != nil || user.Active == 0 {
+		return data.ErrInvalidRememberToken
+	}
+
+	token := m.App.RandStr(32)
+	if err := m.Models.RememberTokens.Rotate(userID, parts[1], token); err != nil {
+		return err
+	}
+
+	if err := m.App.Session.RenewToken(r.Context()); err != nil {
+		return err
+	}
+	m.App.Session.Put(r.Context(), "userID", userID)
+	m.App.Session.Put(r.Context(), "remember_token", token)
+	value = fmt.Sprintf("%d|%s", userID, token)
+	return m.Cookies.SetEncrypted(w, m.App.GetRememberMeCookieName(), value, data.RememberTokenLifetime)
+}
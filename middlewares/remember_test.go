@@ -0,0 +1,117 @@
+package middlewares
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"myapp/cookies"
+	"myapp/data"
+	"myapp/encryption"
+)
+
+// rememberMiddleware returns testMiddleware with a cookie jar, and a
+// remember-me cookie for userID's token.
+func rememberMiddleware(t *testing.T, userID int, token string) (*Middleware, *http.Cookie) {
+	t.Helper()
+	e, err := encryption.New([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Middleware{App: testMiddleware.App, Models: testMiddleware.Models, Cookies: &cookies.Jar{Encrypter: e}}
+
+	rr := httptest.NewRecorder()
+	if err := m.Cookies.SetEncrypted(rr, m.App.GetRememberMeCookieName(), fmt.Sprintf("%d|%s", userID, token), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	return m, rr.Result().Cookies()[0]
+}
+
+// checkRemember sends a request with cookie through CheckRemember and
+// returns the user it logged in, or 0, and the remember-me cookie it set.
+func checkRemember(t *testing.T, m *Middleware, cookie *http.Cookie) (int, *http.Cookie) {
+	t.Helper()
+	var userID int
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = m.App.Session.GetInt(r.Context(), "userID")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(cookie)
+	rr := serve(m.CheckRemember(next), req)
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == m.App.GetRememberMeCookieName() {
+			return userID, c
+		}
+	}
+	return userID, nil
+}
+
+func TestCheckRememberRotatesToken(t *testing.T) {
+	userID := insertUser(t, "remember@example.com")
+	t.Cleanup(func() {
+		_ = testMiddleware.Models.RememberTokens.DeleteForUser(userID)
+	})
+	if err := testMiddleware.Models.RememberTokens.InsertToken(userID, "first-token"); err != nil {
+		t.Fatal(err)
+	}
+	m, cookie := rememberMiddleware(t, userID, "first-token")
+
+	got, rotated := checkRemember(t, m, cookie)
+	if got != userID {
+		t.Fatalf("expected user %d to be logged in, got %d", userID, got)
+	}
+	if rotated == nil || rotated.MaxAge < 0 || rotated.Value == cookie.Value {
+		t.Fatalf("expected a new remember-me cookie, got %v", rotated)
+	}
+	if _, err := m.Models.RememberTokens.Validate(userID, "first-token"); !errors.Is(err, data.ErrInvalidRememberToken) {
+		t.Errorf("expected the used token to be gone, got %v", err)
+	}
+
+	// the new cookie logs in again, and is rotated in turn
+	got, again := checkRemember(t, m, rotated)
+	if got != userID || again == nil || again.MaxAge < 0 || again.Value == rotated.Value {
+		t.Errorf("expected the rotated cookie to log in and be replaced, got user %d and %v", got, again)
+	}
+}
+
+func TestCheckRememberRefusesReplayedToken(t *testing.T) {
+	userID := insertUser(t, "stolen@example.com")
+	t.Cleanup(func() {
+		_ = testMiddleware.Models.RememberTokens.DeleteForUser(userID)
+	})
+	if err := testMiddleware.Models.RememberTokens.InsertToken(userID, "stolen-token"); err != nil {
+		t.Fatal(err)
+	}
+	m, cookie := rememberMiddleware(t, userID, "stolen-token")
+
+	// the real user comes back first
+	if got, _ := checkRemember(t, m, cookie); got != userID {
+		t.Fatalf("expected user %d to be logged in, got %d", userID, got)
+	}
+
+	// a copy of the old cookie no longer works, and is deleted
+	got, deleted := checkRemember(t, m, cookie)
+	if got != 0 {
+		t.Errorf("expected the replayed cookie not to log in, got user %d", got)
+	}
+	if deleted == nil || deleted.MaxAge >= 0 {
+		t.Errorf("expected the replayed cookie to be deleted, got %v", deleted)
+	}
+
+	// nor does one naming another user
+	otherID := insertUser(t, "thief@example.com")
+	if err := testMiddleware.Models.RememberTokens.InsertToken(userID, "victim-token"); err != nil {
+		t.Fatal(err)
+	}
+	_, forged := rememberMiddleware(t, otherID, "victim-token")
+	if got, _ := checkRemember(t, m, forged); got != 0 {
+		t.Errorf("expected a token of another user not to log in, got user %d", got)
+	}
+	if err := m.Models.RememberTokens.Rotate(otherID, "victim-token", "new"); !errors.Is(err, data.ErrInvalidRememberToken) {
+		t.Errorf("expected Rotate to refuse another user's token, got %v", err)
+	}
+}
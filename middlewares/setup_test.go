@@ -0,0 +1,81 @@
+package middlewares
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"myapp/data"
+	"myapp/database/dbtest"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/lozhkindm/celeritas"
+)
+
+var testMiddleware *Middleware
+
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "middlewares-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	d, err := dbtest.Open("..", dir)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	app := &celeritas.Celeritas{
+		InfoLog:  log.New(io.Discard, "", 0),
+		ErrorLog: log.New(os.Stderr, "ERROR\t", log.Lshortfile),
+		RootPath: "..",
+		Session:  scs.New(),
+	}
+	testMiddleware = &Middleware{App: app, Models: data.New(d)}
+
+	code := m.Run()
+	_ = d.Primary.Close()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// passed is the handler behind the middleware under test. It records the
+// requests that get through.
+type passed struct {
+	called bool
+	user   *data.User
+}
+
+func (p *passed) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.called = true
+	p.user = data.UserFromContext(r.Context())
+}
+
+// insertUser creates an active user who is deleted after the test.
+func insertUser(t *testing.T, email string) int {
+	t.Helper()
+	id, err := testMiddleware.Models.Users.Insert(data.User{
+		FirstName: "Middleware",
+		LastName:  "User",
+		Email:     email,
+		Password:  "password",
+		Active:    1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = testMiddleware.Models.Users.Delete(id)
+	})
+	return id
+}
+
+// serve runs h behind the session middleware the real router uses.
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	testMiddleware.App.Session.LoadAndSave(h).ServeHTTP(rr, r)
+	return rr
+}
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+
+	"myapp/urlsigner"
+)
+
+// VerifySignature rejects requests whose URL was not signed with the
+// application key, was altered, or has expired.
+func (m *Middleware) VerifySignature(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signer := urlsigner.Signer{Secret: []byte(m.App.EncryptionKey)}
+		if err := signer.Verify(r.URL.RequestURI()); err != nil {
+			if errors.Is(err, urlsigner.ErrExpired) {
+				http.Error(w, "This link has expired", http.StatusGone)
+				return
+			}
+			m.App.Forbidden(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"myapp/apierror"
+	"myapp/data"
+)
+
+// tokenCacheSeconds bounds how long a revoked or expired token can still be
+// served from the cache by another instance.
+const tokenCacheSeconds = 60
+
+type cachedToken struct {
+	User  data.User
+	Token data.Token
+}
+
+// AuthToken authenticates API requests with an "Authorization: Bearer" token
+// and puts the user and the token in the request context, where handlers get
+// them with data.UserFromContext and data.TokenFromContext.
+func (m *Middleware) AuthToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		plainText, ok := bearerToken(r)
+		if !ok {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			apierror.Write(m.App, w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		user, token, err := m.lookupToken(plainText)
+		if errors.Is(err, data.ErrInvalidToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			apierror.Write(m.App, w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			m.App.ErrorLog.Println("error checking api token:", err)
+			apierror.Write(m.App, w, http.StatusInternalServerError, "internal server error")
+			return
+		}
+
+		ctx := data.WithToken(data.WithUser(r.Context(), user), token)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireScope rejects requests whose API token was not issued with scope.
+// It must run after AuthToken.
+func (m *Middleware) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := data.TokenFromContext(r.Context())
+			if token == nil || !token.HasScope(scope) {
+				apierror.Write(m.App, w, http.StatusForbidden, fmt.Sprintf("token does not have the %s scope", scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// lookupToken checks plainText against the cache, when one is configured, and
+// then against the tokens table.
+func (m *Middleware) lookupToken(plainText string) (*data.User, *data.Token, error) {
+	key := data.TokenCacheKey(plainText)
+
+	if m.App.Cache != nil {
+		if raw, err := m.App.Cache.Get(key); err == nil {
+			var cached cachedToken
+			if s, ok := raw.(string); ok && json.Unmarshal([]byte(s), &cached) == nil && time.Now().Before(cached.Token.ExpiresAt) {
+				return &cached.User, &cached.Token, nil
+			}
+		}
+	}
+
+	user, token, err := m.Models.Tokens.GetUserForToken(plainText)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if m.App.Cache != nil {
+		ttl := tokenCacheSeconds
+		if left := int(time.Until(token.ExpiresAt).Seconds()); left < ttl {
+			ttl = left
+		}
+		if ttl > 0 {
+			b, err := json.Marshal(cachedToken{User: *user, Token: *token})
+			if err == nil {
+				err = m.App.Cache.Set(key, string(b), ttl)
+			}
+			if err != nil {
+				m.App.ErrorLog.Println("error caching api token:", err)
+			}
+		}
+	}
+
+	return user, token, nil
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	parts := strings.Fields(r.Header.Get("Authorization"))
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return "", false
+	}
+	return parts[1], true
+}
@@ -0,0 +1,108 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"myapp/cache"
+)
+
+// withCache gives testMiddleware a memory cache for the rest of the test.
+func withCache(t *testing.T) *cache.Memory {
+	memory := cache.NewMemory(cache.MemoryOptions{})
+	testMiddleware.App.Cache = memory
+	t.Cleanup(func() {
+		testMiddleware.App.Cache = nil
+		_ = memory.Close()
+	})
+	return memory
+}
+
+func authToken(plainText string) (*httptest.ResponseRecorder, *passed) {
+	next := &passed{}
+	req := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+	if plainText != "" {
+		req.Header.Set("Authorization", "Bearer "+plainText)
+	}
+	rr := httptest.NewRecorder()
+	testMiddleware.AuthToken(next).ServeHTTP(rr, req)
+	return rr, next
+}
+
+func TestAuthTokenRefusesMissingToken(t *testing.T) {
+	rr, next := authToken("")
+	if rr.Code != http.StatusUnauthorized || next.called {
+		t.Errorf("expected status %d without a token, got %d", http.StatusUnauthorized, rr.Code)
+	}
+	if rr.Header().Get("WWW-Authenticate") != "Bearer" {
+		t.Errorf("expected a Bearer challenge, got %q", rr.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestAuthTokenRefusesExpiredAndRevokedTokens(t *testing.T) {
+	userID := insertUser(t, "token@example.com")
+	t.Cleanup(func() {
+		_, _ = testMiddleware.Models.Tokens.DeleteForUser(userID)
+	})
+
+	expired, err := testMiddleware.Models.Tokens.Issue(userID, "expired", -time.Minute, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := testMiddleware.Models.Tokens.Issue(userID, "revoked", time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := testMiddleware.Models.Tokens.Delete(revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, plainText := range map[string]string{
+		"expired": expired.PlainText,
+		"revoked": revoked.PlainText,
+		"unknown": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+	} {
+		rr, next := authToken(plainText)
+		if rr.Code != http.StatusUnauthorized || next.called {
+			t.Errorf("%s: expected status %d, got %d", name, http.StatusUnauthorized, rr.Code)
+		}
+		if rr.Header().Get("WWW-Authenticate") != `Bearer error="invalid_token"` {
+			t.Errorf("%s: expected an invalid_token challenge, got %q", name, rr.Header().Get("WWW-Authenticate"))
+		}
+	}
+}
+
+func TestAuthTokenForgetsCachedTokenAfterPasswordReset(t *testing.T) {
+	withCache(t)
+	userID := insertUser(t, "cached-token@example.com")
+
+	token, err := testMiddleware.Models.Tokens.Issue(userID, "cli", time.Hour, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr, next := authToken(token.PlainText)
+	if rr.Code != http.StatusOK || !next.called || next.user == nil || next.user.ID != userID {
+		t.Fatalf("expected the token to authenticate user %d, got %d", userID, rr.Code)
+	}
+
+	// what PostResetPassword does
+	deleted, err := testMiddleware.Models.Tokens.DeleteForUser(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rr, next := authToken(token.PlainText); !next.called {
+		t.Fatalf("expected the cached token to be served until it is forgotten, got %d", rr.Code)
+	}
+	for _, token := range deleted {
+		if err := testMiddleware.App.Cache.Forget(token.CacheKey()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if rr, next := authToken(token.PlainText); rr.Code != http.StatusUnauthorized || next.called {
+		t.Errorf("expected the token to be refused once forgotten, got %d", rr.Code)
+	}
+}
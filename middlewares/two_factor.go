@@ -0,0 +1,31 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"myapp/i18n"
+)
+
+// RequireTwoFactor sends logged in users who have not enabled 2FA to the
+// setup page. Use it after Auth on routes for staff accounts.
+func (m *Middleware) RequireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := m.App.Session.GetInt(r.Context(), "userID")
+		enabled, err := m.Models.TwoFactors.EnabledFor(userID)
+		if err != nil {
+			m.App.ErrorLog.Println("error checking two-factor:", err)
+			m.App.InternalError(w)
+			return
+		}
+		if !enabled {
+			t := i18n.FromContext(r.Context())
+			if t == nil {
+				t = m.Lang.Translator(m.Lang.Default)
+			}
+			m.App.Session.Put(r.Context(), "error", t.T("auth.two_factor_required"))
+			http.Redirect(w, r, "/users/two-factor/setup", http.StatusSeeOther)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
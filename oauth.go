@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+
+	"myapp/oauth"
+)
+
+// oauthProviders returns the identity providers configured in .env, keyed by
+// the name used in their URLs.
+func (a *application) oauthProviders() map[string]oauth.Provider {
+	cfg := a.Config.OAuth
+	providers := make(map[string]oauth.Provider)
+	add := func(p oauth.Provider) {
+		providers[p.Name()] = p
+	}
+	clientConfig := func(name, clientID, clientSecret string) oauth.Config {
+		return oauth.Config{
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			RedirectURL:  strings.TrimRight(a.Config.Server.URL, "/") + "/auth/" + name + "/callback",
+		}
+	}
+
+	if cfg.GitHubClientID != "" {
+		add(oauth.NewGitHub(clientConfig("github", cfg.GitHubClientID, cfg.GitHubClientSecret)))
+	}
+	if cfg.GoogleClientID != "" {
+		add(oauth.NewGoogle(clientConfig("google", cfg.GoogleClientID, cfg.GoogleClientSecret)))
+	}
+	if cfg.OIDCClientID != "" {
+		add(oauth.NewOIDC(cfg.OIDCName, cfg.OIDCIssuer, clientConfig(cfg.OIDCName, cfg.OIDCClientID, cfg.OIDCClientSecret)))
+	}
+	return providers
+}
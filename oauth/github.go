@@ -0,0 +1,81 @@
+package oauth
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// GitHub logs users in with their GitHub account. GitHub is plain OAuth2, so
+// the user and their emails are read from the API.
+type GitHub struct {
+	Config
+	// BaseURL and APIURL are github.com's unless set, for GitHub Enterprise
+	// or tests.
+	BaseURL string
+	APIURL  string
+}
+
+// NewGitHub returns the GitHub provider, asking for the user's email
+// addresses unless cfg sets other scopes.
+func NewGitHub(cfg Config) *GitHub {
+	if len(cfg.Scopes) == 0 {
+		cfg.Scopes = []string{"read:user", "user:email"}
+	}
+	return &GitHub{Config: cfg, BaseURL: "https://github.com", APIURL: "https://api.github.com"}
+}
+
+func (g *GitHub) Name() string {
+	return "github"
+}
+
+func (g *GitHub) AuthURL(_ context.Context, state, _ string) (string, error) {
+	return g.authURL(strings.TrimRight(g.BaseURL, "/")+"/login/oauth/authorize", state, nil), nil
+}
+
+func (g *GitHub) Identify(ctx context.Context, code, _ string) (*Identity, error) {
+	token, err := g.exchange(ctx, strings.TrimRight(g.BaseURL, "/")+"/login/oauth/access_token", code)
+	if err != nil {
+		return nil, err
+	}
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+		Name  string `json:"name"`
+	}
+	api := strings.TrimRight(g.APIURL, "/")
+	if err := g.getJSON(ctx, api+"/user", token.AccessToken, &user); err != nil {
+		return nil, err
+	}
+	if user.ID == 0 {
+		return nil, ErrNoIdentity
+	}
+
+	identity := &Identity{
+		Provider: g.Name(),
+		Subject:  strconv.FormatInt(user.ID, 10),
+		Name:     user.Name,
+	}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+
+	// the profile email is whatever the user made public, so use the
+	// primary address instead, which also says whether it is verified
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := g.getJSON(ctx, api+"/user/emails", token.AccessToken, &emails); err != nil {
+		return nil, err
+	}
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+		}
+	}
+	return identity, nil
+}
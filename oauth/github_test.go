@@ -0,0 +1,48 @@
+package oauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGitHubIdentify(t *testing.T) {
+	verified := false
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"access_token": "access", "token_type": "bearer"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": 42, "login": "octocat"})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, []map[string]interface{}{
+			{"email": "old@example.com", "primary": false, "verified": true},
+			{"email": "octocat@example.com", "primary": true, "verified": verified},
+		})
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	g := NewGitHub(Config{ClientID: "client", ClientSecret: "secret"})
+	g.BaseURL, g.APIURL = srv.URL, srv.URL
+
+	identity, err := g.Identify(context.Background(), "code", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{Provider: "github", Subject: "42", Email: "octocat@example.com", Name: "octocat"}
+	if *identity != want {
+		t.Errorf("expected %+v, got %+v", want, *identity)
+	}
+
+	verified = true
+	if identity, err = g.Identify(context.Background(), "code", ""); err != nil || !identity.EmailVerified {
+		t.Errorf("expected a verified primary email, got %+v, %v", identity, err)
+	}
+}
@@ -0,0 +1,161 @@
+// Package oauth logs users in with an external identity provider, using the
+// OAuth2 authorization code flow or OpenID Connect on top of it.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNoIdentity is returned when the provider does not say who the user is.
+var ErrNoIdentity = errors.New("oauth: provider returned no identity")
+
+// Identity is the user as the provider knows them.
+type Identity struct {
+	Provider      string
+	Subject       string // the provider's stable ID for the user
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider is an identity provider users can log in with. The user is sent to
+// AuthURL and comes back to the redirect URL with a code, which Identify turns
+// into an Identity. nonce is only checked by OpenID Connect providers.
+type Provider interface {
+	Name() string
+	AuthURL(ctx context.Context, state, nonce string) (string, error)
+	Identify(ctx context.Context, code, nonce string) (*Identity, error)
+}
+
+// Config is what every provider needs from its client registration.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// Client makes the requests to the provider. Nil means a client with a
+	// 10 second timeout.
+	Client *http.Client
+}
+
+// RandomString returns a value to use as the state or nonce of a login.
+func RandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	Description string `json:"error_description"`
+}
+
+func (c *Config) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+func (c *Config) authURL(endpoint, state string, extra url.Values) string {
+	q := url.Values{}
+	q.Set("response_type", "code")
+	q.Set("client_id", c.ClientID)
+	q.Set("redirect_uri", c.RedirectURL)
+	q.Set("state", state)
+	if len(c.Scopes) > 0 {
+		q.Set("scope", strings.Join(c.Scopes, " "))
+	}
+	for k, v := range extra {
+		q[k] = v
+	}
+
+	sep := "?"
+	if strings.Contains(endpoint, "?") {
+		sep = "&"
+	}
+	return endpoint + sep + q.Encode()
+}
+
+// exchange trades the code from the redirect for tokens. The client secret
+// is sent in the body, which every provider we know of accepts.
+func (c *Config) exchange(ctx context.Context, endpoint, code string) (*tokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.RedirectURL)
+	form.Set("client_id", c.ClientID)
+	form.Set("client_secret", c.ClientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var token tokenResponse
+	if err := c.do(req, &token); err != nil && token.Error == "" {
+		return nil, err
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("oauth: token exchange failed: %s %s", token.Error, token.Description)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("oauth: token exchange returned no access token")
+	}
+	return &token, nil
+}
+
+// getJSON reads a JSON resource, authenticated with accessToken when it is
+// not empty.
+func (c *Config) getJSON(ctx context.Context, endpoint, accessToken string, dst interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return c.do(req, dst)
+}
+
+// do sends req and decodes the JSON response into dst. The body is decoded
+// even for an error status, so callers can read the provider's error.
+func (c *Config) do(req *http.Request, dst interface{}) error {
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+	}()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	decodeErr := json.Unmarshal(body, dst)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oauth: %s %s returned %s", req.Method, req.URL.Redacted(), resp.Status)
+	}
+	return decodeErr
+}
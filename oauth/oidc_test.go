@@ -0,0 +1,290 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeIssuer is an OpenID Connect provider serving discovery, token and JWKS
+// endpoints. Each code it accepts is answered with an ID token signed by the
+// key kid, with claims changed by edit.
+type fakeIssuer struct {
+	srv *httptest.Server
+
+	mu       sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	kid      string
+	signer   *rsa.PrivateKey // signs instead of keys[kid] when set
+	edit     func(claims map[string]interface{})
+	nonce    string
+	jwksHits int
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+	f := &fakeIssuer{keys: map[string]*rsa.PrivateKey{"key-1": testKey(t)}, kid: "key-1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 f.srv.URL,
+			"authorization_endpoint": f.srv.URL + "/authorize",
+			"token_endpoint":         f.srv.URL + "/token",
+			"jwks_uri":               f.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != "good-code" || r.PostFormValue("client_secret") != "secret" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     f.idToken(t),
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.jwksHits++
+
+		var keys []map[string]string
+		for kid, key := range f.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"keys": keys})
+	})
+	f.srv = httptest.NewServer(mux)
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+func (f *fakeIssuer) provider() *OIDC {
+	return NewOIDC("test", f.srv.URL, Config{ClientID: "client", ClientSecret: "secret", RedirectURL: "http://app/callback"})
+}
+
+func (f *fakeIssuer) idToken(t *testing.T) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	claims := map[string]interface{}{
+		"iss":            f.srv.URL,
+		"sub":            "subject-1",
+		"aud":            "client",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          f.nonce,
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"name":           "Ada Lovelace",
+	}
+	if f.edit != nil {
+		f.edit(claims)
+	}
+
+	header := segment(t, map[string]string{"alg": "RS256", "kid": f.kid})
+	payload := segment(t, claims)
+	key := f.keys[f.kid]
+	if f.signer != nil {
+		key = f.signer
+	}
+	sum := sha256.Sum256([]byte(header + "." + payload))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Error(err)
+	}
+	return header + "." + payload + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func (f *fakeIssuer) set(fn func(f *fakeIssuer)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fn(f)
+}
+
+func (f *fakeIssuer) hits() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.jwksHits
+}
+
+func TestOIDCIdentify(t *testing.T) {
+	f := newFakeIssuer(t)
+	f.set(func(f *fakeIssuer) { f.nonce = "nonce-1" })
+	o := f.provider()
+
+	authURL, err := o.AuthURL(context.Background(), "state-1", "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("state") != "state-1" || q.Get("nonce") != "nonce-1" || q.Get("client_id") != "client" {
+		t.Errorf("unexpected auth URL %s", authURL)
+	}
+
+	identity, err := o.Identify(context.Background(), "good-code", "nonce-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Identity{Provider: "test", Subject: "subject-1", Email: "ada@example.com", EmailVerified: true, Name: "Ada Lovelace"}
+	if *identity != want {
+		t.Errorf("expected %+v, got %+v", want, *identity)
+	}
+
+	if _, err := o.Identify(context.Background(), "bad-code", "nonce-1"); err == nil {
+		t.Error("expected a rejected code to fail")
+	}
+}
+
+func TestOIDCRejectsInvalidIDTokens(t *testing.T) {
+	other := testKey(t)
+	tests := []struct {
+		name  string
+		nonce string
+		setup func(f *fakeIssuer)
+	}{
+		{name: "wrong nonce", nonce: "another-nonce"},
+		{name: "forged signature", setup: func(f *fakeIssuer) { f.signer = other }},
+		{name: "wrong issuer", setup: func(f *fakeIssuer) {
+			f.edit = func(c map[string]interface{}) { c["iss"] = "https://evil.example.com" }
+		}},
+		{name: "wrong audience", setup: func(f *fakeIssuer) {
+			f.edit = func(c map[string]interface{}) { c["aud"] = []string{"someone-else"} }
+		}},
+		{name: "expired", setup: func(f *fakeIssuer) {
+			f.edit = func(c map[string]interface{}) { c["exp"] = time.Now().Add(-2 * clockSkew).Unix() }
+		}},
+		{name: "no subject", setup: func(f *fakeIssuer) {
+			f.edit = func(c map[string]interface{}) { delete(c, "sub") }
+		}},
+		{name: "unknown key", setup: func(f *fakeIssuer) {
+			f.kid = "key-2"
+			f.signer = other
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeIssuer(t)
+			f.set(func(f *fakeIssuer) {
+				f.nonce = "nonce-1"
+				if tt.setup != nil {
+					tt.setup(f)
+				}
+			})
+			nonce := tt.nonce
+			if nonce == "" {
+				nonce = "nonce-1"
+			}
+
+			_, err := f.provider().Identify(context.Background(), "good-code", nonce)
+			if !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("expected ErrInvalidIDToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestOIDCExpiryUsesNow(t *testing.T) {
+	f := newFakeIssuer(t)
+	o := f.provider()
+
+	expiry := time.Now().Add(time.Hour)
+	o.Now = func() time.Time { return expiry.Add(clockSkew / 2) }
+	if _, err := o.Identify(context.Background(), "good-code", ""); err != nil {
+		t.Errorf("expected a token within the clock skew to pass, got %v", err)
+	}
+
+	o.Now = func() time.Time { return expiry.Add(2 * clockSkew) }
+	if _, err := o.Identify(context.Background(), "good-code", ""); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("expected ErrInvalidIDToken past the expiry, got %v", err)
+	}
+}
+
+func TestOIDCRefreshesRotatedKeys(t *testing.T) {
+	f := newFakeIssuer(t)
+	o := f.provider()
+
+	for i := 0; i < 2; i++ {
+		if _, err := o.Identify(context.Background(), "good-code", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if hits := f.hits(); hits != 1 {
+		t.Errorf("expected the key set to be fetched once, got %d", hits)
+	}
+
+	rotated := testKey(t)
+	f.set(func(f *fakeIssuer) {
+		f.keys = map[string]*rsa.PrivateKey{"key-2": rotated}
+		f.kid = "key-2"
+	})
+	if _, err := o.Identify(context.Background(), "good-code", ""); err != nil {
+		t.Fatalf("expected a token signed with a rotated key to pass, got %v", err)
+	}
+	if hits := f.hits(); hits != 2 {
+		t.Errorf("expected the key set to be fetched again, got %d fetches", hits)
+	}
+}
+
+func TestOIDCChecksDiscoveredIssuer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{
+			"issuer":                 "https://evil.example.com",
+			"authorization_endpoint": "https://evil.example.com/authorize",
+			"token_endpoint":         "https://evil.example.com/token",
+			"jwks_uri":               "https://evil.example.com/jwks",
+		})
+	}))
+	defer srv.Close()
+
+	o := NewOIDC("test", srv.URL, Config{ClientID: "client"})
+	if _, err := o.AuthURL(context.Background(), "state", "nonce"); err == nil {
+		t.Error("expected discovery naming another issuer to fail")
+	}
+}
+
+func testKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func segment(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Error(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -2,15 +2,40 @@ package main
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 func (a *application) routes() *chi.Mux {
 	// middlewares
+	a.routeUse(a.Middlewares.CheckRemember)
+	a.routeUse(a.Middlewares.Locale)
 
 	// routes
 	a.routeGet("/", a.Handlers.Home)
+	a.routeGet("/users/login", a.Handlers.UserLogin)
+	a.routePost("/users/login", a.Handlers.PostUserLogin)
+	a.routePost("/users/logout", a.Handlers.Logout)
+	a.routeGet("/users/forgot-password", a.Handlers.Forgot)
+	a.routePost("/users/forgot-password", a.Handlers.PostForgot)
+	a.routeGet("/users/two-factor", a.Handlers.TwoFactor)
+	a.routePost("/users/two-factor", a.Handlers.PostTwoFactor)
+	a.routeGet("/auth/{provider}", a.Handlers.OAuthLogin)
+	a.routeGet("/auth/{provider}/callback", a.Handlers.OAuthCallback)
+	a.routePost("/locale", a.Handlers.SetLocale)
+
+	// routes for logged in users
+	auth := a.App.Routes.With(a.Middlewares.Auth)
+	auth.Get("/users/two-factor/setup", a.Handlers.TwoFactorSetup)
+	auth.Post("/users/two-factor/setup", a.Handlers.PostTwoFactorSetup)
+	auth.Post("/users/two-factor/disable", a.Handlers.PostTwoFactorDisable)
+
+	// routes reached through signed links
+	signed := a.App.Routes.With(a.Middlewares.VerifySignature)
+	signed.Get("/users/reset-password", a.Handlers.ResetPassword)
+	signed.Post("/users/reset-password", a.Handlers.PostResetPassword)
 
 	// static routes
 	fileServer := http.FileServer(http.Dir("./public"))
@@ -18,3 +43,41 @@ func (a *application) routes() *chi.Mux {
 
 	return a.App.Routes
 }
+
+// apiRoutes are authenticated with bearer tokens rather than the session, so
+// they get their own router without the session and CSRF middleware. The
+// CSRF exemption for /api/* would not cover nested paths anyway.
+func (a *application) apiRoutes() *chi.Mux {
+	mux := chi.NewRouter()
+	mux.Use(middleware.RequestID)
+	mux.Use(middleware.RealIP)
+	mux.Use(middleware.Recoverer)
+	if a.App.Debug {
+		mux.Use(middleware.Logger)
+	}
+
+	mux.Route("/api", func(r chi.Router) {
+		r.Post("/tokens", a.Handlers.IssueToken)
+
+		r.Group(func(r chi.Router) {
+			r.Use(a.Middlewares.AuthToken)
+			r.Get("/user", a.Handlers.CurrentUser)
+			r.Delete("/tokens", a.Handlers.RevokeToken)
+			r.Delete("/tokens/{id}", a.Handlers.RevokeToken)
+		})
+	})
+
+	return mux
+}
+
+// handler sends /api requests to the API router and everything else to the
+// web routes.
+func (a *application) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/") {
+			a.API.ServeHTTP(w, r)
+			return
+		}
+		a.App.Routes.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lozhkindm/celeritas/cache"
+)
+
+const defaultShutdownTimeout = 30 * time.Second
+
+// OnShutdown registers cleanup functions that run once the server has stopped
+// accepting requests, before the database and cache connections are closed.
+// Hooks run in reverse order of registration.
+func (a *application) OnShutdown(hooks ...func() error) {
+	a.shutdownHooks = append(a.shutdownHooks, hooks...)
+}
+
+func (a *application) serve() error {
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", a.Config.Server.Port),
+		Handler:      a.handler(),
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: 600 * time.Second,
+		IdleTimeout:  30 * time.Second,
+		ErrorLog:     a.App.ErrorLog,
+	}
+	servers := []*http.Server{srv}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 2)
+
+	if a.Config.Server.Secure {
+		reloader, err := a.certReloader()
+		if err != nil {
+			return err
+		}
+		go reloader.watch(ctx, func(err error) {
+			a.App.ErrorLog.Println("reloading certificate:", err)
+		})
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: reloader.GetCertificate,
+		}
+
+		if port := a.Config.TLS.HTTPRedirectPort; port != 0 {
+			redirect := &http.Server{
+				Addr:         fmt.Sprintf(":%d", port),
+				Handler:      redirectToHTTPS(a.Config.Server.Port),
+				ReadTimeout:  5 * time.Second,
+				WriteTimeout: 5 * time.Second,
+				IdleTimeout:  30 * time.Second,
+				ErrorLog:     a.App.ErrorLog,
+			}
+			servers = append(servers, redirect)
+			go func() {
+				a.App.InfoLog.Printf("Redirecting HTTP on port %d to HTTPS", port)
+				if err := redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					errs <- err
+				}
+			}()
+		}
+	}
+
+	go func() {
+		var err error
+		if a.Config.Server.Secure {
+			a.App.InfoLog.Printf("Listening on port %d (TLS)", a.Config.Server.Port)
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			a.App.InfoLog.Printf("Listening on port %d", a.Config.Server.Port)
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+
+	var serveErr error
+	select {
+	case serveErr = <-errs:
+	case <-ctx.Done():
+		stop()
+		a.App.InfoLog.Println("Shutting down...")
+	}
+
+	if err := a.shutdown(servers...); err != nil && serveErr == nil {
+		serveErr = err
+	}
+	return serveErr
+}
+
+// certReloader loads the certificate configured by TLS_CERT_FILE and
+// TLS_KEY_FILE, generating a self-signed pair first when TLS_SELF_SIGNED is
+// set and the files do not exist yet.
+func (a *application) certReloader() (*certReloader, error) {
+	certFile := a.Config.TLS.CertFile
+	if certFile == "" {
+		certFile = fmt.Sprintf("%s/tls/cert.pem", a.App.RootPath)
+	}
+	keyFile := a.Config.TLS.KeyFile
+	if keyFile == "" {
+		keyFile = fmt.Sprintf("%s/tls/key.pem", a.App.RootPath)
+	}
+
+	if a.Config.TLS.SelfSigned {
+		if _, err := os.Stat(certFile); os.IsNotExist(err) {
+			a.App.InfoLog.Printf("Generating self-signed certificate in %s", certFile)
+			if err := generateSelfSignedCert(certFile, keyFile, a.Config.Server.Name, "localhost", "127.0.0.1"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return newCertReloader(certFile, keyFile)
+}
+
+func (a *application) shutdown(servers ...*http.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout())
+	defer cancel()
+
+	var firstErr error
+	check := func(err error) {
+		if err == nil {
+			return
+		}
+		a.App.ErrorLog.Println(err)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	for _, srv := range servers {
+		check(srv.Shutdown(ctx))
+	}
+
+	if a.App.Scheduler != nil {
+		select {
+		case <-a.App.Scheduler.Stop().Done():
+		case <-ctx.Done():
+			check(errors.New("scheduler did not stop before the shutdown deadline"))
+		}
+	}
+
+	check(a.drainMail(ctx))
+
+	for i := len(a.shutdownHooks) - 1; i >= 0; i-- {
+		check(a.shutdownHooks[i]())
+	}
+
+	if a.App.DB.Pool != nil {
+		check(a.App.DB.Pool.Close())
+	}
+	switch c := a.App.Cache.(type) {
+	case *cache.RedisCache:
+		check(c.Conn.Close())
+	case *cache.BadgerCache:
+		check(c.Conn.Close())
+	}
+
+	return firstErr
+}
+
+// drainMail waits until the mail queued by the handlers has been sent,
+// including the message the mailer is sending right now.
+func (a *application) drainMail(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.mailing.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("mail still unsent at shutdown, %d jobs queued", len(a.App.Mail.Jobs))
+	}
+}
+
+// shutdownTimeout is SHUTDOWN_TIMEOUT, or defaultShutdownTimeout when that is
+// zero or less and would cancel the shutdown before it started.
+func (a *application) shutdownTimeout() time.Duration {
+	if a.Config.Server.ShutdownTimeout <= 0 {
+		return defaultShutdownTimeout
+	}
+	return a.Config.Server.ShutdownTimeout
+}
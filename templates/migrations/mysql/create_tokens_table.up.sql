@@ -0,0 +1,12 @@
+CREATE TABLE tokens (
+    id         INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id    INT UNSIGNED NOT NULL,
+    name       VARCHAR(255) NOT NULL DEFAULT '',
+    token_hash CHAR(64)     NOT NULL UNIQUE,
+    scopes     VARCHAR(255) NOT NULL DEFAULT '',
+    expires_at DATETIME     NOT NULL,
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX tokens_user_id_idx (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
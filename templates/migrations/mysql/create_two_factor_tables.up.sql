@@ -0,0 +1,21 @@
+CREATE TABLE two_factors (
+    id           INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id      INT UNSIGNED NOT NULL UNIQUE,
+    secret       TEXT         NOT NULL,
+    enabled      INT          NOT NULL DEFAULT 0,
+    last_step    BIGINT       NOT NULL DEFAULT 0,
+    failed_codes INT          NOT NULL DEFAULT 0,
+    locked_until BIGINT       NOT NULL DEFAULT 0,
+    created_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
+
+CREATE TABLE recovery_codes (
+    id         INT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    user_id    INT UNSIGNED NOT NULL,
+    code_hash  CHAR(64)     NOT NULL,
+    created_at DATETIME     NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    INDEX recovery_codes_user_id_idx (user_id),
+    FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE
+) ENGINE = InnoDB;
@@ -0,0 +1,10 @@
+CREATE TABLE remember_tokens (
+    id             SERIAL PRIMARY KEY,
+    user_id        INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    remember_token CHAR(64)  NOT NULL UNIQUE,
+    expires_at     TIMESTAMP NOT NULL,
+    created_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at     TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX remember_tokens_user_id_idx ON remember_tokens (user_id);
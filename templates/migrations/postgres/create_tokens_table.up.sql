@@ -0,0 +1,12 @@
+CREATE TABLE tokens (
+    id         SERIAL PRIMARY KEY,
+    user_id    INTEGER      NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name       VARCHAR(255) NOT NULL DEFAULT '',
+    token_hash CHAR(64)     NOT NULL UNIQUE,
+    scopes     VARCHAR(255) NOT NULL DEFAULT '',
+    expires_at TIMESTAMP    NOT NULL,
+    created_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX tokens_user_id_idx ON tokens (user_id);
@@ -0,0 +1,20 @@
+CREATE TABLE two_factors (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id      INTEGER   NOT NULL UNIQUE REFERENCES users (id) ON DELETE CASCADE,
+    secret       TEXT      NOT NULL,
+    enabled      INTEGER   NOT NULL DEFAULT 0,
+    last_step    BIGINT    NOT NULL DEFAULT 0,
+    failed_codes INTEGER   NOT NULL DEFAULT 0,
+    locked_until BIGINT    NOT NULL DEFAULT 0,
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE recovery_codes (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    INTEGER   NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    code_hash  CHAR(64)  NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX recovery_codes_user_id_idx ON recovery_codes (user_id);
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// leaf parses the certificate cr is serving.
+func leaf(t *testing.T, cr *certReloader) *x509.Certificate {
+	t.Helper()
+	cert, err := cr.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls", "cert.pem")
+	keyFile := filepath.Join(dir, "tls", "key.pem")
+
+	if err := generateSelfSignedCert(certFile, keyFile, "localhost", "127.0.0.1", "::1", ""); err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("expected a matching certificate and key, got %v", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, host := range []string{"localhost", "127.0.0.1", "::1"} {
+		if err := cert.VerifyHostname(host); err != nil {
+			t.Errorf("expected the certificate to be valid for %s, got %v", host, err)
+		}
+	}
+	if len(cert.DNSNames) != 1 || len(cert.IPAddresses) != 2 {
+		t.Errorf("expected 1 name and 2 addresses, got %v and %v", cert.DNSNames, cert.IPAddresses)
+	}
+	if now := time.Now(); now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+		t.Errorf("expected the certificate to be valid now, got %v to %v", cert.NotBefore, cert.NotAfter)
+	}
+
+	info, err := os.Stat(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the key to be readable by its owner only, got %v", info.Mode().Perm())
+	}
+}
+
+func TestCertReloaderSwapsChangedCertificate(t *testing.T) {
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := generateSelfSignedCert(certFile, keyFile, "localhost"); err != nil {
+		t.Fatal(err)
+	}
+
+	cr, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := leaf(t, cr)
+	if changed, err := cr.changed(); err != nil || changed {
+		t.Fatalf("expected no change yet, got %v, %v", changed, err)
+	}
+
+	if err := generateSelfSignedCert(certFile, keyFile, "example.com"); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		if err := os.Chtimes(f, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if changed, err := cr.changed(); err != nil || !changed {
+		t.Fatalf("expected the new files to be noticed, got %v, %v", changed, err)
+	}
+	if leaf(t, cr).SerialNumber.Cmp(first.SerialNumber) != 0 {
+		t.Error("expected the old certificate to be served until reloaded")
+	}
+
+	if err := cr.reload(); err != nil {
+		t.Fatal(err)
+	}
+	second := leaf(t, cr)
+	if second.SerialNumber.Cmp(first.SerialNumber) == 0 || second.VerifyHostname("example.com") != nil {
+		t.Error("expected the new certificate to be served")
+	}
+	if changed, _ := cr.changed(); changed {
+		t.Error("expected no change after the reload")
+	}
+
+	// a broken pair keeps the working certificate
+	if err := os.WriteFile(keyFile, []byte("not a key"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := cr.reload(); err == nil {
+		t.Error("expected an invalid key to fail the reload")
+	}
+	if leaf(t, cr).SerialNumber.Cmp(second.SerialNumber) != 0 {
+		t.Error("expected the previous certificate to be kept")
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		host   string
+		port   int
+		target string
+		want   string
+	}{
+		{"example.com", 443, "/posts?page=2&sort=new", "https://example.com/posts?page=2&sort=new"},
+		{"example.com:80", 0, "/", "https://example.com/"},
+		{"example.com:8080", 8443, "/a%20b?q=x%26y", "https://example.com:8443/a%20b?q=x%26y"},
+		{"[::1]:8080", 8443, "/login", "https://[::1]:8443/login"},
+		{"[::1]:80", 443, "/login?next=/", "https://[::1]/login?next=/"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+		r.Host = tt.host
+		rr := httptest.NewRecorder()
+		redirectToHTTPS(tt.port).ServeHTTP(rr, r)
+
+		if rr.Code != http.StatusMovedPermanently {
+			t.Errorf("%s%s: expected status %d, got %d", tt.host, tt.target, http.StatusMovedPermanently, rr.Code)
+		}
+		if got := rr.Header().Get("Location"); got != tt.want {
+			t.Errorf("%s%s: expected %s, got %s", tt.host, tt.target, tt.want, got)
+		}
+	}
+}
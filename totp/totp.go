@@ -0,0 +1,140 @@
+// Package totp implements time-based one-time passwords (RFC 6238), as used by
+// authenticator apps.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultDigits = 6
+	DefaultPeriod = 30 * time.Second
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret returns a new random 160 bit secret, base32 encoded as
+// authenticator apps expect.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// TOTP generates and checks codes for one secret. The zero values of Digits,
+// Period and Now mean 6 digits, 30 seconds and time.Now; Now can be replaced
+// with a fake clock in tests.
+type TOTP struct {
+	Secret []byte
+	Digits int
+	Period time.Duration
+	// Skew is the number of periods before and after the current one whose
+	// codes are still accepted, to allow for clock drift.
+	Skew int
+	Now  func() time.Time
+}
+
+// New returns a TOTP for a base32 secret, accepting codes one period either
+// side of the current one.
+func New(secret string) (*TOTP, error) {
+	key, err := encoding.DecodeString(strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "=")))
+	if err != nil {
+		return nil, errors.New("totp: secret is not valid base32")
+	}
+	return &TOTP{Secret: key, Skew: 1}, nil
+}
+
+// Step returns the number of the period t falls in.
+func (t *TOTP) Step(at time.Time) int64 {
+	return at.Unix() / int64(t.period().Seconds())
+}
+
+// Code returns the code for the period t falls in.
+func (t *TOTP) Code(at time.Time) string {
+	return t.code(t.Step(at))
+}
+
+// Validate checks code against the current period and Skew periods either
+// side of it. It returns the step the code belongs to, so callers can refuse
+// a code that was already used.
+func (t *TOTP) Validate(code string) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != t.digits() {
+		return 0, false
+	}
+
+	current := t.Step(t.now())
+	for i := -t.Skew; i <= t.Skew; i++ {
+		step := current + int64(i)
+		if subtle.ConstantTimeCompare([]byte(t.code(step)), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// URI returns the otpauth:// URI an authenticator app reads from a QR code.
+func (t *TOTP) URI(issuer, account string) string {
+	q := url.Values{}
+	q.Set("secret", encoding.EncodeToString(t.Secret))
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(t.digits()))
+	q.Set("period", fmt.Sprint(int(t.period().Seconds())))
+
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	// some apps show a + in the issuer literally
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, strings.ReplaceAll(q.Encode(), "+", "%20"))
+}
+
+// code is the HOTP value (RFC 4226) for a counter.
+func (t *TOTP) code(counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, t.Secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < t.digits(); i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", t.digits(), value%mod)
+}
+
+func (t *TOTP) digits() int {
+	if t.Digits > 0 {
+		return t.Digits
+	}
+	return DefaultDigits
+}
+
+func (t *TOTP) period() time.Duration {
+	if t.Period > 0 {
+		return t.Period
+	}
+	return DefaultPeriod
+}
+
+func (t *TOTP) now() time.Time {
+	if t.Now != nil {
+		return t.Now()
+	}
+	return time.Now()
+}
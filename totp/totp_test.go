@@ -0,0 +1,66 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// TestCode checks the SHA1 test vectors of RFC 6238, appendix B.
+func TestCode(t *testing.T) {
+	otp := &TOTP{Secret: []byte("12345678901234567890"), Digits: 8}
+	for unix, want := range map[int64]string{
+		59:          "94287082",
+		1111111109:  "07081804",
+		1111111111:  "14050471",
+		1234567890:  "89005924",
+		2000000000:  "69279037",
+		20000000000: "65353130",
+	} {
+		if got := otp.Code(time.Unix(unix, 0)); got != want {
+			t.Errorf("expected code %s at %d, got %s", want, unix, got)
+		}
+	}
+}
+
+func TestValidateAllowsSkew(t *testing.T) {
+	otp, err := New("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	otp.Now = func() time.Time { return now }
+
+	tests := []struct {
+		offset time.Duration
+		ok     bool
+	}{
+		{0, true},
+		{-DefaultPeriod, true},
+		{DefaultPeriod, true},
+		{-2 * DefaultPeriod, false},
+		{2 * DefaultPeriod, false},
+	}
+	for _, tt := range tests {
+		at := now.Add(tt.offset)
+		step, ok := otp.Validate(otp.Code(at))
+		if ok != tt.ok {
+			t.Errorf("expected the code %s away to be accepted: %v, got %v", tt.offset, tt.ok, ok)
+		}
+		if ok && step != otp.Step(at) {
+			t.Errorf("expected step %d for the code %s away, got %d", otp.Step(at), tt.offset, step)
+		}
+	}
+
+	if _, ok := otp.Validate("12345"); ok {
+		t.Error("expected a code with too few digits to be refused")
+	}
+}
+
+func TestNewRejectsInvalidSecret(t *testing.T) {
+	if _, err := New("not base32!"); err == nil {
+		t.Error("expected an invalid secret to be refused")
+	}
+	if _, err := New("gezd gnbv gy3t qojq"); err != nil {
+		t.Errorf("expected a lower case secret with spaces to be accepted, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package urlsigner
+
+import (
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testSigner() *Signer {
+	return &Signer{Secret: []byte("0123456789abcdef0123456789abcdef")}
+}
+
+// sign signs rawURL for an hour and parses the result.
+func sign(t *testing.T, s *Signer, rawURL string) *url.URL {
+	t.Helper()
+	signed, err := s.Sign(rawURL, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(signed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestSignAndVerify(t *testing.T) {
+	s := testSigner()
+	u := sign(t, s, "https://example.com/users/reset-password?email=ada%40example.com&signature=stale")
+
+	q := u.Query()
+	if len(q["signature"]) != 1 || q.Get("signature") == "stale" {
+		t.Errorf("expected one new signature, got %v", q["signature"])
+	}
+	if q.Get("email") != "ada@example.com" || q.Get("expires") == "" {
+		t.Errorf("expected the email and an expiry, got %v", q)
+	}
+
+	if err := s.Verify(u.String()); err != nil {
+		t.Errorf("expected the signed URL to verify, got %v", err)
+	}
+	if err := s.Verify(u.RequestURI()); err != nil {
+		t.Errorf("expected the path and query alone to verify, got %v", err)
+	}
+	u.Host = "proxy.internal:8080"
+	if err := s.Verify(u.String()); err != nil {
+		t.Errorf("expected another host to verify, got %v", err)
+	}
+
+	if _, err := (&Signer{}).Sign("/x", time.Hour); err == nil {
+		t.Error("expected signing without a secret to fail")
+	}
+	if err := (&Signer{}).Verify(u.String()); err == nil || errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected verifying without a secret to fail on its own, got %v", err)
+	}
+}
+
+func TestVerifyRefusesTamperedURL(t *testing.T) {
+	s := testSigner()
+	tests := []struct {
+		name   string
+		tamper func(u *url.URL, q url.Values)
+	}{
+		{"changed value", func(u *url.URL, q url.Values) { q.Set("email", "eve@example.com") }},
+		{"added parameter", func(u *url.URL, q url.Values) { q.Set("admin", "1") }},
+		{"repeated parameter", func(u *url.URL, q url.Values) { q.Add("email", "eve@example.com") }},
+		{"removed parameter", func(u *url.URL, q url.Values) { q.Del("email") }},
+		{"later expiry", func(u *url.URL, q url.Values) {
+			q.Set("expires", q.Get("expires")+"0")
+		}},
+		{"changed path", func(u *url.URL, q url.Values) { u.Path = "/users/delete" }},
+		{"changed signature", func(u *url.URL, q url.Values) {
+			sig := []byte(q.Get("signature"))
+			if sig[0] == '0' {
+				sig[0] = '1'
+			} else {
+				sig[0] = '0'
+			}
+			q.Set("signature", string(sig))
+		}},
+		{"signature not hex", func(u *url.URL, q url.Values) { q.Set("signature", "not-hex") }},
+		{"no signature", func(u *url.URL, q url.Values) { q.Del("signature") }},
+	}
+	for _, tt := range tests {
+		u := sign(t, s, "https://example.com/users/reset-password?email=ada%40example.com")
+		q := u.Query()
+		tt.tamper(u, q)
+		u.RawQuery = q.Encode()
+		if err := s.Verify(u.String()); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", tt.name, err)
+		}
+	}
+
+	u := sign(t, s, "https://example.com/users/reset-password?email=ada%40example.com")
+	other := &Signer{Secret: []byte("another secret")}
+	if err := other.Verify(u.String()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected a URL signed with another secret to be refused, got %v", err)
+	}
+}
+
+func TestVerifyRefusesExpiredURL(t *testing.T) {
+	s := testSigner()
+	signed, err := s.Sign("/users/reset-password?email=ada%40example.com", -time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify(signed); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+
+	// an expiry that is not a number is signed but never valid
+	signed, err = s.Sign("/users/reset-password?email=ada%40example.com", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(signed)
+	q := u.Query()
+	q.Set("expires", "soon")
+	q.Del("signature")
+	u.RawQuery = q.Encode()
+	q.Set("signature", hex.EncodeToString(s.mac(u)))
+	u.RawQuery = q.Encode()
+	if err := s.Verify(u.String()); !errors.Is(err, ErrExpired) {
+		t.Errorf("expected ErrExpired for an expiry that is not a number, got %v", err)
+	}
+}
+
+func TestVerifyIgnoresParameterOrder(t *testing.T) {
+	s := testSigner()
+	u := sign(t, s, "/search?z=last&a=first&m=middle&a=again")
+
+	// the same parameters in another order, as a proxy might send them
+	parts := strings.Split(u.RawQuery, "&")
+	parts = append(parts[len(parts)-1:], parts[:len(parts)-1]...)
+	reordered := u.Path + "?" + strings.Join(parts, "&")
+	if reordered == u.RequestURI() {
+		t.Fatal("expected the query to be reordered")
+	}
+	if err := s.Verify(reordered); err != nil {
+		t.Errorf("expected reordered parameters to verify, got %v", err)
+	}
+
+	// the order of the values of one parameter is part of the signature
+	q := u.Query()
+	q["a"] = []string{"again", "first"}
+	u.RawQuery = q.Encode()
+	if err := s.Verify(u.String()); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected swapped values of a parameter to be refused, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Bind fills dst, a pointer to a struct, from the form values in v.Data by
+// the fields' form tags, then validates it with Struct. Strings, bools,
+// numbers and string slices are filled in; nested structs read values named
+// like address.city. A value that does not fit the field's type is reported
+// as an error on that field, and the field's rules are not checked.
+func (v *Validation) Bind(dst interface{}) {
+	rv := reflect.ValueOf(dst)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: Bind needs a pointer to a struct, got %T", dst))
+	}
+	v.bindStruct(rv.Elem(), "")
+	v.Struct(dst)
+}
+
+func (v *Validation) bindStruct(rv reflect.Value, prefix string) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf, fv := t.Field(i), rv.Field(i)
+		if sf.PkgPath != "" || sf.Tag.Get("form") == "-" {
+			continue
+		}
+		if sf.Anonymous && fv.Kind() == reflect.Struct {
+			v.bindStruct(fv, prefix)
+			continue
+		}
+
+		name := prefix + fieldName(sf)
+		if fv.Kind() == reflect.Struct && fv.Type() != timeType {
+			v.bindStruct(fv, name+".")
+			continue
+		}
+
+		values, ok := v.Data[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+		if rule := setField(fv, values); rule != "" {
+			v.AddError(name, v.Message(rule, name, ""))
+			if v.unbound == nil {
+				v.unbound = make(map[string]bool)
+			}
+			v.unbound[name] = true
+		}
+	}
+}
+
+// setField converts form values to the field's type. When they do not fit
+// it returns the name of the message to show, or "".
+func setField(fv reflect.Value, values []string) string {
+	raw := strings.TrimSpace(values[0])
+
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(values[0])
+	case reflect.Bool:
+		// an unchecked checkbox is not sent at all, a checked one sends "on"
+		b, err := strconv.ParseBool(raw)
+		if raw == "on" {
+			b, err = true, nil
+		}
+		if err != nil {
+			return "bool"
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if raw == "" {
+			return ""
+		}
+		n, err := strconv.ParseInt(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return "int"
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if raw == "" {
+			return ""
+		}
+		n, err := strconv.ParseUint(raw, 10, fv.Type().Bits())
+		if err != nil {
+			return "uint"
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if raw == "" {
+			return ""
+		}
+		f, err := strconv.ParseFloat(raw, fv.Type().Bits())
+		if err != nil {
+			return "number"
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return ""
+		}
+		fv.Set(reflect.ValueOf(append([]string(nil), values...)).Convert(fv.Type()))
+	}
+	return ""
+}
@@ -0,0 +1,95 @@
+package validation
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestBindFillsFields(t *testing.T) {
+	type address struct {
+		City string `form:"city" validate:"required"`
+	}
+	var form struct {
+		Name     string   `form:"name" validate:"required"`
+		Age      int      `form:"age"`
+		Quantity uint     `form:"quantity"`
+		Price    float64  `form:"price"`
+		Remember bool     `form:"remember"`
+		Colors   []string `form:"colors"`
+		Address  address  `form:"address"`
+		Secret   string   `form:"-"`
+	}
+
+	v := New(url.Values{
+		"name":         {" Ada "},
+		"age":          {"36"},
+		"quantity":     {"3"},
+		"price":        {"9.5"},
+		"remember":     {"on"},
+		"colors":       {"red", "blue"},
+		"address.city": {"London"},
+		"Secret":       {"nope"},
+		"-":            {"nope"},
+	})
+	v.Bind(&form)
+
+	if !v.IsValid() {
+		t.Fatalf("expected a valid form, got %v", v.Errors)
+	}
+	if form.Name != " Ada " || form.Age != 36 || form.Quantity != 3 || form.Price != 9.5 || !form.Remember {
+		t.Errorf("expected the scalar fields to be filled, got %+v", form)
+	}
+	if len(form.Colors) != 2 || form.Colors[0] != "red" || form.Colors[1] != "blue" {
+		t.Errorf("expected both colors, got %v", form.Colors)
+	}
+	if form.Address.City != "London" {
+		t.Errorf("expected address.city to fill the nested struct, got %q", form.Address.City)
+	}
+	if form.Secret != "" {
+		t.Errorf("expected a field tagged form:\"-\" to be left alone, got %q", form.Secret)
+	}
+}
+
+func TestBindReportsValuesOfTheWrongType(t *testing.T) {
+	var form struct {
+		Age      int     `form:"age" validate:"required,min=18"`
+		Quantity uint    `form:"quantity"`
+		Price    float64 `form:"price"`
+		Remember bool    `form:"remember"`
+		Page     int     `form:"page" validate:"min=1"`
+	}
+
+	v := New(url.Values{
+		"age":      {"old"},
+		"quantity": {"-1"},
+		"price":    {"cheap"},
+		"remember": {"maybe"},
+		"page":     {""},
+	})
+	v.Bind(&form)
+
+	want := map[string]string{
+		"age":      "Field 'age' must be an integer",
+		"quantity": "Field 'quantity' must be a positive integer",
+		"price":    "Field 'price' must be a number",
+		"remember": "Field 'remember' must be true or false",
+	}
+	for field, msg := range want {
+		if got := v.FieldErrors[field]; len(got) != 1 || got[0] != msg {
+			t.Errorf("expected only %q for %s, got %q", msg, field, got)
+		}
+	}
+	if len(v.Errors) != len(want) {
+		t.Errorf("expected an empty page to be left unset and unchecked, got %v", v.Errors)
+	}
+}
+
+func TestBindPanicsWithoutStructPointer(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Bind to panic for a struct value")
+		}
+	}()
+	var form struct{}
+	New(url.Values{}).Bind(form)
+}
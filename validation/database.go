@@ -0,0 +1,100 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Database answers the unique and exists rules. data.Lookup implements it
+// with the application's database.
+type Database interface {
+	// Count returns how many rows of table have value in column, not
+	// counting the row with id exceptID when it is not 0.
+	Count(table, column string, value interface{}, exceptID int) (int, error)
+}
+
+var database Database
+
+// UseDatabase sets the database the unique and exists rules query.
+func UseDatabase(db Database) {
+	database = db
+}
+
+// Err returns the first error met while querying the database. When it is
+// not nil the input could not be fully checked, and the request should fail
+// with a server error rather than a validation message.
+func (v *Validation) Err() error {
+	return v.err
+}
+
+// Unique checks that no row of table has value in column, except the row with
+// id exceptID, such as the record being edited. Pass 0 to check every row.
+func (v *Validation) Unique(field, table, column string, value interface{}, exceptID int) {
+	if msg := v.unique(field, table, column, value, exceptID); msg != "" {
+		v.AddError(field, msg)
+	}
+}
+
+// Exists checks that some row of table has value in column.
+func (v *Validation) Exists(field, table, column string, value interface{}) {
+	if msg := v.exists(field, table, column, value); msg != "" {
+		v.AddError(field, msg)
+	}
+}
+
+func (v *Validation) unique(field, table, column string, value interface{}, exceptID int) string {
+	n, err := v.count(table, column, value, exceptID)
+	if err == nil && n > 0 {
+		return v.Message("unique", field, "")
+	}
+	return ""
+}
+
+func (v *Validation) exists(field, table, column string, value interface{}) string {
+	n, err := v.count(table, column, value, 0)
+	if err == nil && n == 0 {
+		return v.Message("exists", field, "")
+	}
+	return ""
+}
+
+func (v *Validation) count(table, column string, value interface{}, exceptID int) (int, error) {
+	if database == nil {
+		panic("validation: no database; call validation.UseDatabase first")
+	}
+	n, err := database.Count(table, column, value, exceptID)
+	if err != nil && v.err == nil {
+		v.err = err
+	}
+	return n, err
+}
+
+func unique(c *check) string {
+	args := strings.Fields(c.param)
+	if len(args) != 2 && len(args) != 3 {
+		panic(fmt.Sprintf("validation: unique on field %s needs a table, a column and optionally an ID field", c.field))
+	}
+
+	exceptID := 0
+	if len(args) == 3 {
+		id := c.parent.FieldByName(args[2])
+		switch id.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			exceptID = int(id.Int())
+		default:
+			panic(fmt.Sprintf("validation: unique on field %s: %s is not an int field", c.field, args[2]))
+		}
+	}
+
+	return c.v.unique(c.field, args[0], args[1], c.value.Interface(), exceptID)
+}
+
+func exists(c *check) string {
+	args := strings.Fields(c.param)
+	if len(args) != 2 {
+		panic(fmt.Sprintf("validation: exists on field %s needs a table and a column", c.field))
+	}
+
+	return c.v.exists(c.field, args[0], args[1], c.value.Interface())
+}
@@ -0,0 +1,60 @@
+package validation
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"myapp/data"
+	"myapp/database/dbtest"
+)
+
+func TestUniqueAndExists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validation-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.RemoveAll(dir)
+	}()
+	d, err := dbtest.Open("..", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = d.Primary.Close()
+	}()
+
+	models := data.New(d)
+	UseDatabase(data.Lookup{})
+	defer UseDatabase(nil)
+
+	id, err := models.Users.Insert(data.User{FirstName: "Alan", LastName: "Turing", Email: "alan@example.com", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type signup struct {
+		ID      int
+		Email   string `form:"email" validate:"unique=users email ID"`
+		Referer string `form:"referer" validate:"exists=users email"`
+	}
+
+	v := New(nil)
+	v.Struct(signup{Email: "alan@example.com", Referer: "nobody@example.com"})
+	if v.Err() != nil {
+		t.Fatal(v.Err())
+	}
+	if v.Errors["email"] == "" {
+		t.Error("expected a taken email to fail unique")
+	}
+	if v.Errors["referer"] == "" {
+		t.Error("expected an unknown referer to fail exists")
+	}
+
+	v = New(nil)
+	v.Struct(signup{ID: id, Email: "alan@example.com", Referer: "alan@example.com"})
+	if !v.IsValid() {
+		t.Errorf("expected the user's own row to be excepted, got %v", v.Errors)
+	}
+}
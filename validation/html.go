@@ -0,0 +1,28 @@
+package validation
+
+import (
+	"html/template"
+	"strings"
+)
+
+// ErrorsHTML renders the messages of field as Bootstrap invalid feedback,
+// to be placed right after the field's input. It renders nothing for a
+// valid field or a nil Validation.
+func (v *Validation) ErrorsHTML(field string) template.HTML {
+	var b strings.Builder
+	for _, m := range v.ErrorsFor(field) {
+		b.WriteString(`<div class="invalid-feedback d-block">`)
+		b.WriteString(template.HTMLEscapeString(m))
+		b.WriteString("</div>\n")
+	}
+	return template.HTML(b.String())
+}
+
+// InvalidClass returns "is-invalid", the Bootstrap class for an input in
+// error, when field has a message, and "" otherwise.
+func (v *Validation) InvalidClass(field string) string {
+	if len(v.ErrorsFor(field)) > 0 {
+		return "is-invalid"
+	}
+	return ""
+}
@@ -0,0 +1,42 @@
+package validation
+
+import (
+	"io"
+	"reflect"
+
+	"github.com/CloudyKit/jet/v6"
+)
+
+// AddJetFuncs adds fieldErrors and invalidClass to views. A handler that
+// re-renders a form puts its Validation in td.Data["validation"], and the
+// form shows the errors next to each input:
+//
+//	<input name="email" class="form-control {{invalidClass(.Data["validation"], "email")}}">
+//	{{fieldErrors(.Data["validation"], "email")}}
+//
+// Both render nothing when the page has no Validation yet.
+func AddJetFuncs(views *jet.Set) {
+	views.AddGlobalFunc("fieldErrors", func(args jet.Arguments) reflect.Value {
+		args.RequireNumOfArguments("fieldErrors", 2, 2)
+		html := jetValidation(args).ErrorsHTML(args.Get(1).String())
+		// a renderer writes the markup as is, past Jet's escaping
+		return reflect.ValueOf(jet.RendererFunc(func(r *jet.Runtime) {
+			_, _ = io.WriteString(r.Writer, string(html))
+		}))
+	})
+	views.AddGlobalFunc("invalidClass", func(args jet.Arguments) reflect.Value {
+		args.RequireNumOfArguments("invalidClass", 2, 2)
+		return reflect.ValueOf(jetValidation(args).InvalidClass(args.Get(1).String()))
+	})
+}
+
+// jetValidation returns the Validation passed to a helper, or nil when the
+// view has none, as before its form is submitted.
+func jetValidation(args jet.Arguments) *Validation {
+	arg := args.Get(0)
+	if !arg.IsValid() || !arg.CanInterface() {
+		return nil
+	}
+	v, _ := arg.Interface().(*Validation)
+	return v
+}
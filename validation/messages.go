@@ -0,0 +1,112 @@
+package validation
+
+import (
+	"strings"
+	"sync"
+)
+
+// DefaultLocale is the locale of the built-in messages, and the one used
+// when a Validation's locale has no message for a rule.
+const DefaultLocale = "en"
+
+// Messages maps a rule name to its message template. In a template, {field}
+// is replaced by the field's label and {param} by the rule's parameter, such
+// as the 3 of min=3. Size rules have a key per kind of value: min for
+// numbers, min.string for strings and min.items for slices and maps.
+//
+// A key of the form field.<name> gives the label shown for a field, such as
+// "field.email": "E-Mail-Adresse". Without one the field's name is shown.
+type Messages map[string]string
+
+var english = Messages{
+	"required":   "Field '{field}' is required",
+	"email":      "Field '{field}' must be a valid email address",
+	"int":        "Field '{field}' must be an integer",
+	"uint":       "Field '{field}' must be a positive integer",
+	"float":      "Field '{field}' must be a float",
+	"number":     "Field '{field}' must be a number",
+	"bool":       "Field '{field}' must be true or false",
+	"date":       "Field '{field}' must be a date in the form of {param}",
+	"datetime":   "Field '{field}' must be a date and time such as 2006-01-02T15:04:05Z",
+	"nospaces":   "Field '{field}' must not contain whitespaces",
+	"min":        "Field '{field}' must be at least {param}",
+	"min.string": "Field '{field}' must be at least {param} characters long",
+	"min.items":  "Field '{field}' must have at least {param} items",
+	"max":        "Field '{field}' must be at most {param}",
+	"max.string": "Field '{field}' must be at most {param} characters long",
+	"max.items":  "Field '{field}' must have at most {param} items",
+	"len":        "Field '{field}' must be exactly {param}",
+	"len.string": "Field '{field}' must be exactly {param} characters long",
+	"len.items":  "Field '{field}' must have exactly {param} items",
+	"oneof":      "Field '{field}' must be one of: {param}",
+	"url":        "Field '{field}' must be a valid URL",
+	"uuid":       "Field '{field}' must be a valid UUID",
+	"regex":      "Field '{field}' is not in the right format",
+	"unique":     "Field '{field}' has already been taken",
+	"exists":     "Field '{field}' does not match an existing record",
+	"match":      "Field '{field}' does not match {param}",
+	"invalid":    "Field '{field}' is invalid",
+}
+
+var (
+	catalogsMu sync.RWMutex
+	catalogs   = map[string]Messages{DefaultLocale: english}
+)
+
+// SetMessages adds messages to those of locale, replacing any with the same
+// key. Call it at startup, once per locale the application supports, to
+// translate the built-in messages or reword them.
+func SetMessages(locale string, messages Messages) {
+	locale = normalizeLocale(locale)
+
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+
+	merged := Messages{}
+	for k, m := range catalogs[locale] {
+		merged[k] = m
+	}
+	for k, m := range messages {
+		merged[k] = m
+	}
+	catalogs[locale] = merged
+}
+
+// Message returns the message of rule for field in v's locale.
+func (v *Validation) Message(rule, field, param string) string {
+	label := field
+	if l, ok := lookup(v.Locale, "field."+field); ok {
+		label = l
+	}
+
+	template, ok := lookup(v.Locale, rule)
+	if !ok {
+		template, _ = lookup(v.Locale, "invalid")
+	}
+	return strings.NewReplacer("{field}", label, "{param}", param).Replace(template)
+}
+
+// lookup finds key for locale, then for its language alone, as pt for
+// pt-BR, then for DefaultLocale.
+func lookup(locale, key string) (string, bool) {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+
+	locale = normalizeLocale(locale)
+	candidates := []string{locale}
+	if i := strings.Index(locale, "-"); i > 0 {
+		candidates = append(candidates, locale[:i])
+	}
+	candidates = append(candidates, DefaultLocale)
+
+	for _, l := range candidates {
+		if m, ok := catalogs[l][key]; ok {
+			return m, true
+		}
+	}
+	return "", false
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+}
@@ -0,0 +1,161 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/asaskevich/govalidator"
+)
+
+// rule checks a non-empty value against the parameter given in the tag, as
+// in min=3. It returns the message for an invalid value, or "".
+type rule func(c *check) string
+
+// check is one field being checked by one rule.
+type check struct {
+	v      *Validation
+	field  string
+	value  reflect.Value
+	param  string
+	parent reflect.Value // the struct the field belongs to
+}
+
+// fail returns the message of rule, showing param as its parameter.
+func (c *check) fail(rule, param string) string {
+	return c.v.Message(rule, c.field, param)
+}
+
+var rules = map[string]rule{
+	"min":      sizeRule("min", func(n, limit float64) bool { return n >= limit }),
+	"max":      sizeRule("max", func(n, limit float64) bool { return n <= limit }),
+	"len":      sizeRule("len", func(n, limit float64) bool { return n == limit }),
+	"oneof":    oneOf,
+	"email":    email,
+	"url":      isURL,
+	"uuid":     isUUID,
+	"date":     date,
+	"datetime": datetime,
+	"regex":    matches,
+	"unique":   unique,
+	"exists":   exists,
+}
+
+var (
+	uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	patterns    sync.Map
+)
+
+// sizeRule compares the length of strings, slices and maps, or the value of
+// numbers, with the parameter.
+func sizeRule(name string, ok func(n, limit float64) bool) rule {
+	return func(c *check) string {
+		field, value, param := c.field, c.value, c.param
+		limit, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validation: invalid parameter %q for field %s", param, field))
+		}
+
+		var n float64
+		key := name
+		switch value.Kind() {
+		case reflect.String:
+			n, key = float64(utf8.RuneCountInString(value.String())), name+".string"
+		case reflect.Slice, reflect.Map, reflect.Array:
+			n, key = float64(value.Len()), name+".items"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			n = float64(value.Int())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			n = float64(value.Uint())
+		case reflect.Float32, reflect.Float64:
+			n = value.Float()
+		default:
+			panic(fmt.Sprintf("validation: cannot check the size of field %s", field))
+		}
+
+		if ok(n, limit) {
+			return ""
+		}
+		return c.fail(key, param)
+	}
+}
+
+func oneOf(c *check) string {
+	options := strings.Fields(c.param)
+	s := stringValue(c.value)
+	for _, o := range options {
+		if s == o {
+			return ""
+		}
+	}
+	return c.fail("oneof", strings.Join(options, ", "))
+}
+
+func email(c *check) string {
+	if govalidator.IsEmail(stringValue(c.value)) {
+		return ""
+	}
+	return c.fail("email", "")
+}
+
+func isURL(c *check) string {
+	u, err := url.ParseRequestURI(stringValue(c.value))
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		return ""
+	}
+	return c.fail("url", "")
+}
+
+func isUUID(c *check) string {
+	if uuidPattern.MatchString(stringValue(c.value)) {
+		return ""
+	}
+	return c.fail("uuid", "")
+}
+
+// date accepts YYYY-MM-DD, or the Go layout given as the parameter.
+func date(c *check) string {
+	layout, shown := c.param, c.param
+	if layout == "" {
+		layout, shown = "2006-01-02", "YYYY-MM-DD"
+	}
+	if _, err := time.Parse(layout, stringValue(c.value)); err == nil {
+		return ""
+	}
+	return c.fail("date", shown)
+}
+
+func datetime(c *check) string {
+	if _, err := time.Parse(time.RFC3339, stringValue(c.value)); err == nil {
+		return ""
+	}
+	return c.fail("datetime", "")
+}
+
+func matches(c *check) string {
+	re, ok := patterns.Load(c.param)
+	if !ok {
+		re, _ = patterns.LoadOrStore(c.param, regexp.MustCompile(c.param))
+	}
+	if re.(*regexp.Regexp).MatchString(stringValue(c.value)) {
+		return ""
+	}
+	return c.fail("regex", "")
+}
+
+func stringValue(value reflect.Value) string {
+	if value.Kind() == reflect.String {
+		return value.String()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
@@ -0,0 +1,189 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Struct validates s, a struct or a pointer to one, by the validate tags of
+// its fields:
+//
+//	required       must not be empty, blank, zero or false
+//	min=N, max=N   at least or at most N characters for strings, items for
+//	               slices and maps, or the value itself for numbers
+//	len=N          exactly N characters or items
+//	oneof=a b c    one of the listed words
+//	email, url, uuid
+//	date           a date as YYYY-MM-DD, or date=<Go layout>
+//	datetime       an RFC 3339 date and time
+//	regex=PATTERN  must match PATTERN, which may contain commas, so this
+//	               rule has to come last
+//	unique=TABLE COLUMN [ID]
+//	               no row of TABLE has the value in COLUMN, not counting
+//	               the row whose id is in the struct field named ID
+//	exists=TABLE COLUMN
+//	               some row of TABLE has the value in COLUMN
+//
+// Rules are separated by commas, as in validate:"required,min=3". A field
+// that is empty and not required is not checked further; otherwise every
+// rule is checked and each failing one adds its message. Nested structs and
+// slices of structs are validated too, with their errors under names such as
+// address.city and items.0.name. Fields are named after their form tag, their
+// json tag or, failing both, their Go name.
+func (v *Validation) Struct(s interface{}) {
+	rv := reflect.ValueOf(s)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validation: Struct needs a struct, got %T", s))
+	}
+	v.validateStruct(rv, "")
+}
+
+func (v *Validation) validateStruct(rv reflect.Value, prefix string) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf, fv := t.Field(i), rv.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+		if sf.Anonymous && fv.Kind() == reflect.Struct {
+			v.validateStruct(fv, prefix)
+			continue
+		}
+
+		tag := sf.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		name := prefix + fieldName(sf)
+		v.validateField(name, fv, tag, rv)
+		v.validateNested(name, fv)
+	}
+}
+
+func (v *Validation) validateField(name string, fv reflect.Value, tag string, parent reflect.Value) {
+	if v.unbound[name] {
+		return
+	}
+	for fv.Kind() == reflect.Ptr && !fv.IsNil() {
+		fv = fv.Elem()
+	}
+	empty := isEmpty(fv)
+
+	for _, r := range parseTag(tag) {
+		if r.name == "required" {
+			if empty {
+				v.AddError(name, v.Message("required", name, ""))
+				return
+			}
+			continue
+		}
+		if empty {
+			return
+		}
+
+		apply, ok := rules[r.name]
+		if !ok {
+			panic(fmt.Sprintf("validation: unknown rule %q on field %s", r.name, name))
+		}
+		c := &check{v: v, field: name, value: fv, param: r.param, parent: parent}
+		if msg := apply(c); msg != "" {
+			v.AddError(name, msg)
+		}
+	}
+}
+
+// validateNested descends into struct fields and slices of structs.
+func (v *Validation) validateNested(name string, fv reflect.Value) {
+	for fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return
+		}
+		fv = fv.Elem()
+	}
+
+	switch fv.Kind() {
+	case reflect.Struct:
+		if fv.Type() != timeType {
+			v.validateStruct(fv, name+".")
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < fv.Len(); i++ {
+			elem := fv.Index(i)
+			for elem.Kind() == reflect.Ptr && !elem.IsNil() {
+				elem = elem.Elem()
+			}
+			if elem.Kind() == reflect.Struct && elem.Type() != timeType {
+				v.validateStruct(elem, name+"."+strconv.Itoa(i)+".")
+			}
+		}
+	}
+}
+
+type tagRule struct {
+	name  string
+	param string
+}
+
+func parseTag(tag string) []tagRule {
+	var parsed []tagRule
+	for tag != "" {
+		part := tag
+		if strings.HasPrefix(tag, "regex=") {
+			tag = ""
+		} else if i := strings.Index(tag, ","); i >= 0 {
+			part, tag = tag[:i], tag[i+1:]
+		} else {
+			tag = ""
+		}
+
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		r := tagRule{name: part}
+		if i := strings.Index(part, "="); i >= 0 {
+			r.name, r.param = part[:i], part[i+1:]
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed
+}
+
+func fieldName(sf reflect.StructField) string {
+	for _, key := range []string{"form", "json"} {
+		name := strings.Split(sf.Tag.Get(key), ",")[0]
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return sf.Name
+}
+
+func isEmpty(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.String:
+		return isBlank(fv.String())
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return fv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return fv.IsNil()
+	case reflect.Struct:
+		if fv.Type() == timeType {
+			return fv.Interface().(time.Time).IsZero()
+		}
+		return false
+	default:
+		return fv.IsZero()
+	}
+}
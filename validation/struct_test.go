@@ -0,0 +1,238 @@
+package validation
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStructRules(t *testing.T) {
+	type input struct {
+		Name    string    `json:"name" validate:"required,min=3,max=10"`
+		Age     int       `json:"age" validate:"min=18,max=130"`
+		Score   float64   `json:"score" validate:"min=0,max=1"`
+		Tags    []string  `json:"tags" validate:"max=2"`
+		Code    string    `json:"code" validate:"len=4"`
+		Role    string    `json:"role" validate:"oneof=admin editor"`
+		Email   string    `json:"email" validate:"email"`
+		Site    string    `json:"site" validate:"url"`
+		ID      string    `json:"id" validate:"uuid"`
+		Born    string    `json:"born" validate:"date"`
+		Month   string    `json:"month" validate:"date=01/2006"`
+		At      string    `json:"at" validate:"datetime"`
+		Slug    string    `json:"slug" validate:"regex=^[a-z]+(-[a-z]+){0,2}$"`
+		Agreed  bool      `json:"agreed" validate:"required"`
+		Start   time.Time `json:"start" validate:"required"`
+		Skipped string    `json:"skipped" validate:"-"`
+	}
+
+	valid := input{
+		Name:   "Ada",
+		Age:    36,
+		Score:  0.5,
+		Tags:   []string{"a", "b"},
+		Code:   "ÄBCD",
+		Role:   "editor",
+		Email:  "ada@example.com",
+		Site:   "https://example.com/ada",
+		ID:     "123e4567-e89b-12d3-a456-426614174000",
+		Born:   "1815-12-10",
+		Month:  "12/1815",
+		At:     "1815-12-10T08:00:00Z",
+		Slug:   "ada-lovelace",
+		Agreed: true,
+		Start:  time.Date(1815, 12, 10, 0, 0, 0, 0, time.UTC),
+	}
+	v := New(nil)
+	v.Struct(&valid)
+	if !v.IsValid() {
+		t.Fatalf("expected a valid struct, got %v", v.Errors)
+	}
+
+	invalid := input{
+		Name:  "Al",
+		Age:   12,
+		Score: 1.5,
+		Tags:  []string{"a", "b", "c"},
+		Code:  "ABC",
+		Role:  "owner",
+		Email: "not an email",
+		Site:  "ftp://example.com",
+		ID:    "123e4567",
+		Born:  "10.12.1815",
+		Month: "1815-12",
+		At:    "1815-12-10 08:00",
+		Slug:  "Ada Lovelace",
+	}
+	v = New(nil)
+	v.Struct(invalid)
+	want := map[string]string{
+		"name":   "Field 'name' must be at least 3 characters long",
+		"age":    "Field 'age' must be at least 18",
+		"score":  "Field 'score' must be at most 1",
+		"tags":   "Field 'tags' must have at most 2 items",
+		"code":   "Field 'code' must be exactly 4 characters long",
+		"role":   "Field 'role' must be one of: admin, editor",
+		"email":  "Field 'email' must be a valid email address",
+		"site":   "Field 'site' must be a valid URL",
+		"id":     "Field 'id' must be a valid UUID",
+		"born":   "Field 'born' must be a date in the form of YYYY-MM-DD",
+		"month":  "Field 'month' must be a date in the form of 01/2006",
+		"at":     "Field 'at' must be a date and time such as 2006-01-02T15:04:05Z",
+		"slug":   "Field 'slug' is not in the right format",
+		"agreed": "Field 'agreed' is required",
+		"start":  "Field 'start' is required",
+	}
+	for field, msg := range want {
+		if got := v.Errors[field]; got != msg {
+			t.Errorf("expected %s to fail with %q, got %q", field, msg, got)
+		}
+	}
+	if len(v.Errors) != len(want) {
+		t.Errorf("expected %d invalid fields, got %v", len(want), v.Errors)
+	}
+}
+
+func TestStructSkipsEmptyOptionalFields(t *testing.T) {
+	var s struct {
+		Name  string `validate:"required,max=3"`
+		Email string `validate:"email,min=100"`
+		Note  string `validate:"min=5"`
+	}
+	s.Note = "   "
+
+	v := New(nil)
+	v.Struct(&s)
+	if len(v.Errors) != 1 || v.FieldErrors["Name"][0] != "Field 'Name' is required" {
+		t.Errorf("expected only Name to be required, got %v", v.FieldErrors)
+	}
+}
+
+func TestStructKeepsEveryErrorOfAField(t *testing.T) {
+	s := struct {
+		Password string `form:"password" validate:"min=8,regex=[0-9]"`
+	}{"abc"}
+
+	v := New(nil)
+	v.Struct(s)
+	want := []string{
+		"Field 'password' must be at least 8 characters long",
+		"Field 'password' is not in the right format",
+	}
+	got := v.FieldErrors["password"]
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+	if v.Errors["password"] != want[0] {
+		t.Errorf("expected the first message in Errors, got %q", v.Errors["password"])
+	}
+}
+
+func TestStructValidatesNestedStructsAndSlices(t *testing.T) {
+	type address struct {
+		City string `json:"city" validate:"required"`
+		Zip  string `json:"zip" validate:"regex=^[0-9]{5}$"`
+	}
+	type Audit struct {
+		CreatedBy string `json:"created_by" validate:"required"`
+	}
+	type order struct {
+		Audit
+		Address  address    `json:"address"`
+		Billing  *address   `json:"billing"`
+		Items    []address  `json:"items" validate:"min=1"`
+		Pointers []*address `json:"pointers"`
+	}
+
+	o := order{
+		Address:  address{Zip: "123"},
+		Billing:  &address{City: "Berlin", Zip: "10115"},
+		Items:    []address{{City: "Paris", Zip: "75001"}, {Zip: "75"}},
+		Pointers: []*address{nil, {}},
+	}
+	v := New(nil)
+	v.Struct(&o)
+
+	for _, field := range []string{
+		"created_by",
+		"address.city",
+		"address.zip",
+		"items.1.city",
+		"items.1.zip",
+		"pointers.1.city",
+	} {
+		if _, ok := v.Errors[field]; !ok {
+			t.Errorf("expected an error for %s, got %v", field, v.Errors)
+		}
+	}
+	if len(v.Errors) != 6 {
+		t.Errorf("expected 6 invalid fields, got %v", v.Errors)
+	}
+}
+
+func TestStructValidatesDecodedJSON(t *testing.T) {
+	type request struct {
+		Email  string   `json:"email" validate:"required,email"`
+		Scopes []string `json:"scopes" validate:"max=1"`
+		Name   string   `json:"name,omitempty" validate:"required"`
+	}
+
+	var req request
+	body := `{"email": "someone", "scopes": ["read", "write"]}`
+	if err := json.NewDecoder(strings.NewReader(body)).Decode(&req); err != nil {
+		t.Fatal(err)
+	}
+	v := New(nil)
+	v.Struct(&req)
+
+	want := map[string]string{
+		"email":  "Field 'email' must be a valid email address",
+		"scopes": "Field 'scopes' must have at most 1 items",
+		"name":   "Field 'name' is required",
+	}
+	for field, msg := range want {
+		if v.Errors[field] != msg {
+			t.Errorf("expected %q for %s, got %q", msg, field, v.Errors[field])
+		}
+	}
+}
+
+func TestStructPanicsOnUnknownRule(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected an unknown rule to panic")
+		}
+	}()
+	s := struct {
+		Name string `validate:"shiny"`
+	}{"x"}
+	New(nil).Struct(s)
+}
+
+func TestMessagesInLocale(t *testing.T) {
+	SetMessages("de", Messages{
+		"required":    "'{field}' ist ein Pflichtfeld",
+		"field.email": "E-Mail-Adresse",
+	})
+	t.Cleanup(func() {
+		catalogsMu.Lock()
+		delete(catalogs, "de")
+		catalogsMu.Unlock()
+	})
+
+	s := struct {
+		Email string `json:"email" validate:"required"`
+		Age   int    `json:"age" validate:"min=18"`
+	}{Age: 3}
+	v := New(nil)
+	v.Locale = "de_AT"
+	v.Struct(s)
+
+	if v.Errors["email"] != "'E-Mail-Adresse' ist ein Pflichtfeld" {
+		t.Errorf("expected the German message for de_AT, got %q", v.Errors["email"])
+	}
+	if v.Errors["age"] != "Field 'age' must be at least 18" {
+		t.Errorf("expected the English message when German has none, got %q", v.Errors["age"])
+	}
+}
@@ -0,0 +1,113 @@
+// Package validation checks user input and collects the messages for every
+// invalid field. It keeps the methods of celeritas.Validation, adds
+// validation of structs by their tags, and words its messages from
+// templates that can be translated and overridden with SetMessages.
+package validation
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/asaskevich/govalidator"
+)
+
+type Validation struct {
+	Data url.Values
+	// Errors holds the first message of each invalid field.
+	Errors map[string]string
+	// FieldErrors holds every message of each invalid field, in order.
+	FieldErrors map[string][]string
+	// Locale picks the messages, as set with SetMessages.
+	Locale string
+
+	err     error           // the first database error, see Err
+	unbound map[string]bool // fields Bind could not fill
+}
+
+// New returns a Validation for form values. data may be nil when only
+// structs are validated, such as a decoded JSON body.
+func New(data url.Values) *Validation {
+	return &Validation{
+		Data:        data,
+		Errors:      make(map[string]string),
+		FieldErrors: make(map[string][]string),
+		Locale:      DefaultLocale,
+	}
+}
+
+func (v *Validation) IsValid() bool {
+	return len(v.Errors) == 0
+}
+
+// AddError records message for field. The first message of a field is also
+// its entry in Errors; the same message is not recorded twice.
+func (v *Validation) AddError(field, message string) {
+	for _, m := range v.FieldErrors[field] {
+		if m == message {
+			return
+		}
+	}
+	v.FieldErrors[field] = append(v.FieldErrors[field], message)
+	if _, ok := v.Errors[field]; !ok {
+		v.Errors[field] = message
+	}
+}
+
+// ErrorsFor returns every message recorded for field. It may be called on a
+// nil Validation, as in a view rendered before the form was submitted.
+func (v *Validation) ErrorsFor(field string) []string {
+	if v == nil {
+		return nil
+	}
+	return v.FieldErrors[field]
+}
+
+func (v *Validation) Has(field string, r *http.Request) bool {
+	return r.Form.Get(field) != ""
+}
+
+func (v *Validation) Required(r *http.Request, fields ...string) {
+	for _, f := range fields {
+		if isBlank(r.Form.Get(f)) {
+			v.AddError(f, v.Message("required", f, ""))
+		}
+	}
+}
+
+func (v *Validation) Check(cond bool, field, message string) {
+	if !cond {
+		v.AddError(field, message)
+	}
+}
+
+func (v *Validation) IsEmail(field, value string) {
+	if !govalidator.IsEmail(value) {
+		v.AddError(field, v.Message("email", field, ""))
+	}
+}
+
+func (v *Validation) IsInt(field, value string) {
+	if _, err := strconv.Atoi(value); err != nil {
+		v.AddError(field, v.Message("int", field, ""))
+	}
+}
+
+func (v *Validation) IsFloat(field, value string) {
+	if _, err := strconv.ParseFloat(value, 64); err != nil {
+		v.AddError(field, v.Message("float", field, ""))
+	}
+}
+
+func (v *Validation) IsDateISO(field, value string) {
+	if _, err := time.Parse("2006-01-02", value); err != nil {
+		v.AddError(field, v.Message("date", field, "YYYY-MM-DD"))
+	}
+}
+
+func (v *Validation) NoSpaces(field, value string) {
+	if govalidator.HasWhitespace(value) {
+		v.AddError(field, v.Message("nospaces", field, ""))
+	}
+}
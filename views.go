@@ -0,0 +1,13 @@
+package main
+
+import "myapp/validation"
+
+// setupViews adds the skeleton's helpers to every Jet view: fieldErrors and
+// invalidClass, which show the errors of a form next to its inputs, as
+// described at validation.AddJetFuncs.
+func (a *application) setupViews() {
+	if a.App.JetViews == nil {
+		return
+	}
+	validation.AddJetFuncs(a.App.JetViews)
+}